@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sirdesai22/sync-service/internal/jobs"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"github.com/sirdesai22/sync-service/internal/workers"
+)
+
+// maxDLQJobs caps how many bulk DLQ jobs may run at once.
+const maxDLQJobs = 2
+
+// dlqBulkHandler serves POST /api/dlq/{retry,resolve,purge} with a body of
+// {"filter": workers.DLQFilter, "concurrency": n}. The matching rows are
+// processed by an async job; its progress and DLQBulkResult summary are
+// available under /api/jobs/{id}. GET /api/dlq/{id} returns one row with its
+// attempt history.
+func dlqBulkHandler(ctx context.Context, worker *workers.SyncWorker, registry *jobs.Registry) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/dlq/"), 10, 64); err == nil && r.Method == http.MethodGet {
+			var row models.DLQ
+			if err := worker.DB.First(&row, "id = ?", id).Error; err != nil {
+				http.Error(rw, "not found", http.StatusNotFound)
+				return
+			}
+			var attempts []models.DLQAttempt
+			worker.DB.Where("dlq_id = ?", id).Order("id").Find(&attempts)
+			json.NewEncoder(rw).Encode(map[string]any{"dlq": row, "attempts": attempts})
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req workers.DLQBulkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(rw, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Action = strings.TrimPrefix(r.URL.Path, "/api/dlq/")
+		switch req.Action {
+		case workers.DLQRetryAll, workers.DLQResolveAll:
+			unresolved := false // only open rows can be retried or resolved
+			req.Filter.Resolved = &unresolved
+		case workers.DLQPurgeAll:
+			if req.Filter == (workers.DLQFilter{}) {
+				http.Error(rw, workers.ErrEmptyDLQFilter.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(rw, "unknown action "+req.Action, http.StatusNotFound)
+			return
+		}
+		startDLQJob(ctx, worker, registry, req, rw)
+	}
+}
+
+// startDLQJob runs req as an async job and answers with its snapshot, unless
+// maxDLQJobs are already running.
+func startDLQJob(ctx context.Context, worker *workers.SyncWorker, registry *jobs.Registry, req workers.DLQBulkRequest, rw http.ResponseWriter) {
+	admit := func(running []jobs.Snapshot) error {
+		if len(running) >= maxDLQJobs {
+			return fmt.Errorf("%d DLQ jobs already running", len(running))
+		}
+		return nil
+	}
+	j, err := registry.StartIf(ctx, "dlq", req, admit, func(ctx context.Context, j *jobs.Job) (any, error) {
+		if n, err := workers.CountDLQ(ctx, worker.DB, req.Filter); err == nil {
+			j.SetTotal(n)
+		}
+		switch req.Action {
+		case workers.DLQRetryAll:
+			return worker.RetryDLQRows(ctx, req.Filter, req.Concurrency, func(ok, failed int64) {
+				j.AddProcessed(ok + failed)
+				j.AddFailed(failed)
+			})
+		case workers.DLQResolveAll:
+			return workers.ResolveDLQRows(ctx, worker.DB, req.Filter, j.AddProcessed)
+		default:
+			return workers.PurgeDLQRows(ctx, worker.DB, req.Filter, j.AddProcessed)
+		}
+	})
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	rw.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(rw).Encode(j.Snapshot())
+}
+
+// dlqGroupsHandler serves the fingerprint view of the DLQ:
+//
+//	GET  /api/dlq/groups                          groups of unresolved rows (?resolved=true|all, ?entity_type=, ?category=, ?limit=)
+//	POST /api/dlq/groups/{fingerprint}/retry      retries every unresolved row of a group
+//	POST /api/dlq/groups/{fingerprint}/resolve    resolves every unresolved row of a group
+func dlqGroupsHandler(ctx context.Context, worker *workers.SyncWorker, registry *jobs.Registry) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/dlq/groups"), "/")
+
+		if rest == "" {
+			if r.Method != http.MethodGet {
+				http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			q := r.URL.Query()
+			f := workers.DLQFilter{EntityType: q.Get("entity_type"), Category: q.Get("category")}
+			switch q.Get("resolved") {
+			case "all":
+			case "true":
+				resolved := true
+				f.Resolved = &resolved
+			default:
+				resolved := false
+				f.Resolved = &resolved
+			}
+			limit, _ := strconv.Atoi(q.Get("limit"))
+			if limit <= 0 || limit > 500 {
+				limit = 100
+			}
+			groups, err := workers.GroupDLQ(r.Context(), worker.DB, f, limit)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(rw).Encode(groups)
+			return
+		}
+
+		fingerprint, action, ok := strings.Cut(rest, "/")
+		if !ok || r.Method != http.MethodPost || (action != workers.DLQRetryAll && action != workers.DLQResolveAll) {
+			http.Error(rw, "use POST /api/dlq/groups/{fingerprint}/{retry,resolve}", http.StatusNotFound)
+			return
+		}
+		unresolved := false
+		startDLQJob(ctx, worker, registry, workers.DLQBulkRequest{
+			Action: action,
+			Filter: workers.DLQFilter{Fingerprint: fingerprint, Resolved: &unresolved},
+		}, rw)
+	}
+}
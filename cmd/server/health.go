@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	elasticsearch "github.com/elastic/go-elasticsearch/v8"
+	"github.com/sirdesai22/sync-service/internal/db"
+	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/health"
+	"github.com/sirdesai22/sync-service/internal/leader"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"github.com/sirdesai22/sync-service/internal/workers"
+	"gorm.io/gorm"
+)
+
+const (
+	healthCheckTimeout = 2 * time.Second
+	// maxHeartbeatAge is how old the worker heartbeat may be before the
+	// service is reported not ready; the loop beats every 5s when idle.
+	maxHeartbeatAge = 30 * time.Second
+)
+
+type componentStatus struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency,omitempty"`
+	Detail  any    `json:"detail,omitempty"`
+}
+
+type serviceStatus struct {
+	Ready      bool                       `json:"ready"`
+	Reason     string                     `json:"reason,omitempty"`
+	Instance   string                     `json:"instance"`
+	Components map[string]componentStatus `json:"components"`
+	Outbox     *workers.OutboxBacklog     `json:"outbox,omitempty"`
+	OpenDLQ    *int64                     `json:"open_dlq,omitempty"`
+	Leader     *leaderStatus              `json:"leader,omitempty"`
+}
+
+type leaderStatus struct {
+	Lease      string     `json:"lease"`
+	Instance   string     `json:"instance"`
+	IsLeader   bool       `json:"is_leader"`
+	Holder     string     `json:"holder,omitempty"`
+	AcquiredAt *time.Time `json:"acquired_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Expired    bool       `json:"expired"`
+}
+
+// currentLeader combines this instance's view with the lease row, which
+// names the leader even when it is another instance.
+func currentLeader(ctx context.Context, elector *leader.Elector) (leaderStatus, error) {
+	st := leaderStatus{Lease: elector.Name, Instance: elector.ID, IsLeader: elector.IsLeader()}
+	l, err := elector.Current(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	st.Holder, st.AcquiredAt, st.ExpiresAt = l.Holder, &l.AcquiredAt, &l.ExpiresAt
+	st.Expired = time.Now().After(l.ExpiresAt)
+	return st, nil
+}
+
+// checkStatus probes every dependency readiness depends on: Postgres,
+// Elasticsearch cluster health, the managed aliases and the worker heartbeat.
+// detailed adds the outbox backlog, open DLQ count and current leader.
+func checkStatus(ctx context.Context, pg *gorm.DB, es *elasticsearch.Client, worker *workers.SyncWorker, elector *leader.Elector, readiness *health.Readiness, detailed bool) serviceStatus {
+	ready, reason := readiness.Get()
+	st := serviceStatus{Instance: worker.ID, Components: map[string]componentStatus{}}
+
+	st.Components["postgres"] = probe(ctx, func(ctx context.Context) (any, error) {
+		return nil, db.Ping(ctx, pg)
+	})
+	st.Components["elasticsearch"] = probe(ctx, func(ctx context.Context) (any, error) {
+		status, err := elastic.ClusterHealth(ctx, es)
+		if err == nil && status == "red" {
+			err = fmt.Errorf("cluster health is red")
+		}
+		return map[string]string{"cluster_status": status}, err
+	})
+	st.Components["indices"] = probe(ctx, func(ctx context.Context) (any, error) {
+		aliases := map[string][]string{}
+		for _, s := range elastic.Specs() {
+			indices, err := elastic.ResolveAlias(ctx, es, s.Alias)
+			if err != nil {
+				return aliases, err
+			}
+			if len(indices) == 0 {
+				return aliases, fmt.Errorf("alias %s is missing", s.Alias)
+			}
+			aliases[s.Alias] = indices
+		}
+		return aliases, nil
+	})
+
+	beat, last := worker.Heartbeat(), worker.LastBatch()
+	w := componentStatus{OK: !beat.IsZero() && time.Since(beat) < maxHeartbeatAge}
+	detail := map[string]any{"running": !beat.IsZero()}
+	if !beat.IsZero() {
+		detail["heartbeat"] = beat
+		detail["heartbeat_age"] = time.Since(beat).Round(time.Millisecond).String()
+	}
+	if !last.IsZero() {
+		detail["last_batch_at"] = last
+	}
+	if p := worker.Partitions(); p != nil {
+		detail["partitions"] = map[string]any{"total": p.Count(), "owned": p.Owned(), "members": p.Members()}
+	}
+	w.Detail = detail
+	if !w.OK {
+		w.Error = "no recent heartbeat from the worker loop"
+	}
+	st.Components["worker"] = w
+
+	st.Ready = ready
+	st.Reason = reason
+	for name, c := range st.Components {
+		if !c.OK {
+			st.Ready = false
+			if st.Reason == "" {
+				st.Reason = name + ": " + c.Error
+			}
+		}
+	}
+
+	if detailed {
+		cctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		defer cancel()
+		if b, err := workers.GetOutboxBacklog(cctx, pg); err == nil {
+			st.Outbox = &b
+		}
+		var open int64
+		if err := pg.WithContext(cctx).Model(&models.DLQ{}).Where("resolved = false").Count(&open).Error; err == nil {
+			st.OpenDLQ = &open
+		}
+		if l, err := currentLeader(cctx, elector); err == nil {
+			st.Leader = &l
+		}
+	}
+	return st
+}
+
+func probe(ctx context.Context, check func(context.Context) (any, error)) componentStatus {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	detail, err := check(ctx)
+	c := componentStatus{OK: err == nil, Latency: time.Since(start).Round(time.Millisecond).String(), Detail: detail}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	return c
+}
+
+// healthzHandler is the liveness probe: the process is up and serving.
+func healthzHandler(rw http.ResponseWriter, _ *http.Request) {
+	rw.Write([]byte("ok"))
+}
+
+// probeTargets is what the readiness checks probe. It is published once
+// startup has connected to everything; until then /readyz only reports the
+// startup reason.
+type probeTargets struct {
+	pg      *gorm.DB
+	es      *elasticsearch.Client
+	worker  *workers.SyncWorker
+	elector *leader.Elector
+}
+
+// readyzHandler is the readiness probe: 200 once startup finished and every
+// dependency check passes, 503 with the failing checks (or why startup has
+// not finished) otherwise.
+func readyzHandler(targets *atomic.Pointer[probeTargets], readiness *health.Readiness) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		t := targets.Load()
+		if t == nil {
+			_, reason := readiness.Get()
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(rw).Encode(serviceStatus{Reason: reason})
+			return
+		}
+		st := checkStatus(r.Context(), t.pg, t.es, t.worker, t.elector, readiness, false)
+		if !st.Ready {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(rw).Encode(st)
+	}
+}
+
+// statusHandler serves GET /api/status: the readiness checks plus the outbox
+// backlog, for humans and dashboards.
+func statusHandler(pg *gorm.DB, es *elasticsearch.Client, worker *workers.SyncWorker, elector *leader.Elector, readiness *health.Readiness) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(rw).Encode(checkStatus(r.Context(), pg, es, worker, elector, readiness, true))
+	}
+}
+
+// leaderHandler serves GET /api/leader: which instance runs the singleton
+// jobs, and whether it is this one.
+func leaderHandler(elector *leader.Elector) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+		st, err := currentLeader(ctx, elector)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(rw).Encode(st)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	elasticsearch "github.com/elastic/go-elasticsearch/v8"
+	"github.com/sirdesai22/sync-service/internal/db"
+	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/jobs"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"github.com/sirdesai22/sync-service/internal/workers"
+	"gorm.io/gorm"
+)
+
+// migrateIndexCommand implements `server migrate-index [flags] <alias>`. The
+// sync service should keep running meanwhile: its workers dual-write into
+// the new index while this process backfills it. With -abort it instead
+// fails the alias's in-progress migration, e.g. after this command crashed.
+func migrateIndexCommand(args []string) {
+	fs := flag.NewFlagSet("migrate-index", flag.ExitOnError)
+	opts := workers.MigrateOptions{}
+	fs.BoolVar(&opts.DeleteOld, "delete-old", false, "delete the previous index after the alias swap")
+	fs.BoolVar(&opts.Force, "force", false, "swap even if document counts differ")
+	fs.Float64Var(&opts.MaxRate, "rate", 0, "max backfill documents per second (0 = unthrottled)")
+	abort := fs.Bool("abort", false, "fail the alias's in-progress migration and delete the index it was building")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s migrate-index [flags] <alias>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	opts.Alias = fs.Arg(0)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pg := db.Connect()
+	db.Migrate(pg)
+	es := elastic.Connect()
+	loadSyncConfig(ctx, pg, es)
+
+	m := &workers.IndexMigrator{DB: pg, ES: es}
+	if *abort {
+		mig, err := m.Abort(ctx, opts.Alias)
+		if err != nil {
+			log.Fatalf("❌ abort migration of %s failed: %v", opts.Alias, err)
+		}
+		log.Printf("✅ %s migration aborted: %s is still live, %s deleted", mig.Alias, mig.FromIndex, mig.ToIndex)
+		return
+	}
+	mig, err := m.Migrate(ctx, opts)
+	if err != nil {
+		log.Fatalf("❌ migrate-index %s failed: %v", opts.Alias, err)
+	}
+	log.Printf("✅ %s migrated: %s -> %s (%d documents)", mig.Alias, mig.FromIndex, mig.ToIndex, mig.ToCount)
+}
+
+type indexStatus struct {
+	Alias           string                 `json:"alias"`
+	DeclaredVersion int                    `json:"declared_version"`
+	Indices         []string               `json:"indices"`
+	LastMigration   *models.IndexMigration `json:"last_migration,omitempty"`
+}
+
+// indicesHandler serves GET /api/indices: every managed alias, where it
+// points and its latest migration.
+func indicesHandler(pg *gorm.DB, es *elasticsearch.Client) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		var out []indexStatus
+		for _, s := range elastic.Specs() {
+			st := indexStatus{Alias: s.Alias, DeclaredVersion: s.Version}
+			indices, err := elastic.ResolveAlias(r.Context(), es, s.Alias)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusBadGateway)
+				return
+			}
+			st.Indices = indices
+			var mig models.IndexMigration
+			if err := pg.Where("alias = ?", s.Alias).Order("id desc").Limit(1).Find(&mig).Error; err == nil && mig.ID != 0 {
+				st.LastMigration = &mig
+			}
+			out = append(out, st)
+		}
+		json.NewEncoder(rw).Encode(out)
+	}
+}
+
+// migrateIndexHandler serves POST /api/indices/migrate with a
+// workers.MigrateOptions body, running the migration as a job.
+func migrateIndexHandler(ctx context.Context, pg *gorm.DB, es *elasticsearch.Client, registry *jobs.Registry) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var opts workers.MigrateOptions
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			http.Error(rw, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !elastic.IsManagedAlias(opts.Alias) {
+			http.Error(rw, "unknown index alias: "+opts.Alias, http.StatusBadRequest)
+			return
+		}
+		j := registry.Start(ctx, "migrate-index", opts, func(ctx context.Context, j *jobs.Job) (any, error) {
+			m := &workers.IndexMigrator{DB: pg, ES: es, OnProgress: j.SetMessage}
+			return m.Migrate(ctx, opts)
+		})
+		rw.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(rw).Encode(j.Snapshot())
+	}
+}
+
+// abortMigrationHandler serves POST /api/indices/migrate/abort with an
+// {"alias": ...} body, aborting that alias's in-progress migration as a job.
+func abortMigrationHandler(ctx context.Context, pg *gorm.DB, es *elasticsearch.Client, registry *jobs.Registry) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body struct {
+			Alias string `json:"alias"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(rw, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !elastic.IsManagedAlias(body.Alias) {
+			http.Error(rw, "unknown index alias: "+body.Alias, http.StatusBadRequest)
+			return
+		}
+		j := registry.Start(ctx, "abort-migration", body, func(ctx context.Context, j *jobs.Job) (any, error) {
+			m := &workers.IndexMigrator{DB: pg, ES: es, OnProgress: j.SetMessage}
+			return m.Abort(ctx, body.Alias)
+		})
+		rw.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(rw).Encode(j.Snapshot())
+	}
+}
+
+// checkMappingDrift applies additive drift and returns the aliases that still
+// have breaking differences.
+func checkMappingDrift(ctx context.Context, es *elasticsearch.Client) ([]string, error) {
+	drifts, err := elastic.CheckMappings(ctx, es, true)
+	if err != nil {
+		return nil, err
+	}
+	var breaking []string
+	for _, d := range drifts {
+		if d.Breaking() {
+			breaking = append(breaking, d.Alias)
+		}
+	}
+	return breaking, nil
+}
+
+// mappingsHandler serves GET /api/mappings (report drift only) and
+// POST /api/mappings/apply (also put additive changes).
+func mappingsHandler(es *elasticsearch.Client) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		apply := r.URL.Path == "/api/mappings/apply"
+		if apply && r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		drifts, err := elastic.CheckMappings(r.Context(), es, apply)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(rw).Encode(drifts)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/sirdesai22/sync-service/internal/jobs"
+)
+
+// jobsHandler serves GET /api/jobs (optionally ?kind=) and GET /api/jobs/{id}
+// for every async admin job, plus DELETE /api/jobs/{id} to cancel one.
+func jobsHandler(registry *jobs.Registry) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/jobs"), "/")
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			json.NewEncoder(rw).Encode(registry.List(r.URL.Query().Get("kind")))
+		case r.Method == http.MethodGet:
+			j, ok := registry.Get(id)
+			if !ok {
+				http.Error(rw, "not found", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(rw).Encode(j.Snapshot())
+		case r.Method == http.MethodDelete && id != "":
+			if !registry.Cancel(id) {
+				http.Error(rw, "not found", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(rw).Encode(map[string]string{"status": "canceling"})
+		default:
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -3,32 +3,63 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/rs/cors"
 
-	"github.com/elastic/go-elasticsearch/v8/esutil"
+	elasticsearch "github.com/elastic/go-elasticsearch/v8"
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirdesai22/sync-service/internal/db"
 	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/health"
+	"github.com/sirdesai22/sync-service/internal/jobs"
+	"github.com/sirdesai22/sync-service/internal/leader"
 	"github.com/sirdesai22/sync-service/internal/models"
 	"github.com/sirdesai22/sync-service/internal/services"
+	"github.com/sirdesai22/sync-service/internal/syncconfig"
 
 	"github.com/sirdesai22/sync-service/internal/metrics"
 	"github.com/sirdesai22/sync-service/internal/workers"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
+// shutdownReleaseMargin is the part of SHUTDOWN_TIMEOUT kept back from the
+// worker's drain for releasing claims and leaving the partitions.
+const shutdownReleaseMargin = 5 * time.Second
+
+// drainTimeout is the worker's share of the shutdown budget: everything but
+// shutdownReleaseMargin, and at least a second.
+func drainTimeout(shutdown time.Duration) time.Duration {
+	return max(shutdown-shutdownReleaseMargin, time.Second)
+}
+
 func main() {
 	_ = godotenv.Load()
 
-	pg := db.Connect()
-	db.Migrate(pg)
-	db.Seed(pg)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "reindex":
+			reindexCommand(os.Args[2:])
+			return
+		case "migrate-index":
+			migrateIndexCommand(os.Args[2:])
+			return
+		case "reconcile":
+			reconcileCommand(os.Args[2:])
+			return
+		}
+	}
 
 	metrics.Register()
 
@@ -39,6 +70,28 @@ func main() {
 		AllowCredentials: true,
 	})
 
+	// The probes answer from the first moment, so an orchestrator does not
+	// kill the process while startup waits for its dependencies: /healthz is
+	// 200 right away and /readyz 503 "starting" until the worker runs. The
+	// other routes are added to the mux once startup is done.
+	readiness := health.NewReadiness()
+	var targets atomic.Pointer[probeTargets]
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler(&targets, readiness))
+	srv := &http.Server{Addr: ":8080", Handler: corsMiddleware.Handler(mux)}
+	go func() {
+		log.Println("🧭 Admin API running on :8080")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("admin API listener failed: %v", err)
+		}
+	}()
+
+	pg := db.Connect()
+	db.Migrate(pg)
+	db.Seed(pg)
+
 	// ✅ Simulate a user update
 	// userID := uuid.MustParse("5a05617f-377e-4d42-832c-ce51fc0c58d8")
 	// err := services.UpdateUser(pg, userID, map[string]any{"college": "IIT Delhi"})
@@ -54,13 +107,60 @@ func main() {
 	// 	log.Fatalf("❌ user update failed: %v", err)
 	// }
 
+	// Startup order matters: db.Connect and elastic.Connect wait for their
+	// dependency to be healthy, indices are created or verified next, and only
+	// then does the worker start and the service report ready. Starting the
+	// worker earlier would let writes auto-create dynamically mapped indices.
 	es := elastic.Connect()
-	worker := &workers.SyncWorker{DB: pg, ES: es}
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	worker := workers.NewSyncWorker(pg, es)
+	// SIGINT/SIGTERM cancel ctx: the worker stops claiming and drains, async
+	// jobs stop, and the admin API shuts down (see the end of main)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// the drain gets the shutdown budget minus enough time to release the
+	// remaining claims and leave the partitions before main gives up
+	worker.DrainTimeout = drainTimeout(health.ShutdownTimeout())
 
-	go worker.Run(ctx)
-	// go worker.RetryDLQ(ctx) // disabled: retry cycle handled manually via admin API
+	// singleton jobs (DLQ retries, scheduled reconciliation, requested
+	// reindexes) run on one instance only, whichever holds this lease
+	elector := leader.New(pg, "singleton-jobs", worker.ID)
+	worker.Leader = elector
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		elector.Run(ctx)
+	}()
+
+	loadSyncConfig(ctx, pg, es)
+	// writes go through aliases, so they must exist before the worker runs
+	if err := elastic.EnsureIndexes(ctx, es); err != nil {
+		log.Fatalf("❌ failed to ensure indices: %v", err)
+	}
+	jobRegistry := jobs.NewRegistry()
+
+	workerDone := make(chan struct{})
+	// additive mapping changes are applied in place; breaking ones would make
+	// every write fail under "dynamic":"strict", so the worker stays off
+	if breaking, err := checkMappingDrift(ctx, es); err != nil {
+		log.Fatalf("❌ mapping drift check failed: %v", err)
+	} else if len(breaking) > 0 && os.Getenv("SYNC_ALLOW_MAPPING_DRIFT") != "true" {
+		log.Printf("❌ breaking mapping drift on %v: sync worker NOT started. Run migrate-index or set SYNC_ALLOW_MAPPING_DRIFT=true", breaking)
+		readiness.Set(false, fmt.Sprintf("breaking mapping drift on %v", breaking))
+		close(workerDone)
+	} else {
+		go func() {
+			defer close(workerDone)
+			worker.Run(ctx)
+		}()
+		readiness.Set(true, "")
+		log.Println("✅ service ready")
+	}
+	startReconcileSchedule(ctx, pg, es, elector)
+	// cascades over the fan-out limit leave reindex requests behind
+	elector.WhileLeader(ctx, "requested reindexes", func(ctx context.Context) {
+		(&workers.Reindexer{DB: pg, ES: es}).ServeRequests(ctx)
+	})
+	targets.Store(&probeTargets{pg: pg, es: es, worker: worker, elector: elector})
 
 	// --- test: update user -> outbox event -> worker -> ES
 	// var user models.User
@@ -69,8 +169,8 @@ func main() {
 	// }
 	// _ = services.UpdateUser(pg, user.ID, map[string]any{"college": "IIT Tirupati"})
 
-	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/api/status", statusHandler(pg, es, worker, elector, readiness))
+	mux.HandleFunc("/api/leader", leaderHandler(elector))
 	mux.HandleFunc("/api/outbox", func(w http.ResponseWriter, r *http.Request) {
 		var outboxes []models.Outbox
 		pg.Order("id desc").Limit(100).Find(&outboxes)
@@ -86,39 +186,45 @@ func main() {
 		// log.Printf("DLQ query returned %d rows", len(dlq))
 		json.NewEncoder(w).Encode(dlq)
 	})
+	mux.HandleFunc("/api/dlq/", dlqBulkHandler(ctx, worker, jobRegistry))
+	mux.HandleFunc("/api/dlq/groups", dlqGroupsHandler(ctx, worker, jobRegistry))
+	mux.HandleFunc("/api/dlq/groups/", dlqGroupsHandler(ctx, worker, jobRegistry))
 	mux.HandleFunc("/api/retry/", func(rw http.ResponseWriter, r *http.Request) {
-		id := r.URL.Path[len("/api/retry/"):]
-		var dlqEntry models.DLQ
-		if err := pg.First(&dlqEntry, "id = ?", id).Error; err != nil {
-			http.Error(rw, "not found", http.StatusNotFound)
+		id, err := strconv.ParseInt(r.URL.Path[len("/api/retry/"):], 10, 64)
+		if err != nil {
+			http.Error(rw, "invalid id", http.StatusBadRequest)
 			return
 		}
 
-		var ob models.Outbox
-		if err := pg.First(&ob, "id = ?", dlqEntry.OutboxID).Error; err != nil {
-			http.Error(rw, "outbox missing", http.StatusInternalServerError)
+		// waits until Elasticsearch has answered and the row reflects it
+		res, err := worker.RetryDLQEntry(r.Context(), id)
+		switch {
+		case errors.Is(err, workers.ErrDLQResolved):
+			http.Error(rw, err.Error(), http.StatusNotFound)
 			return
-		}
-
-		bi, _ := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
-			Client: es, Index: "", FlushBytes: 5 << 20, NumWorkers: 2,
-		})
-		if err := worker.ApplyEvent(ctx, bi, ob); err != nil {
+		case err != nil:
 			http.Error(rw, "retry failed: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		now := time.Now()
-		if err := pg.Model(&models.DLQ{}).
-			Where("id = ?", id).
-			Updates(map[string]any{"resolved": true, "retried_at": &now}).Error; err != nil {
-			http.Error(rw, "update failed: "+err.Error(), http.StatusInternalServerError)
-			return
+		if !res.Resolved {
+			rw.WriteHeader(http.StatusBadGateway)
 		}
-
-		json.NewEncoder(rw).Encode(map[string]string{"status": "retried"})
+		json.NewEncoder(rw).Encode(res)
 	})
 
+	mux.HandleFunc("/api/reindex", reindexHandler(ctx, pg, es, jobRegistry))
+	mux.HandleFunc("/api/reindex/", reindexHandler(ctx, pg, es, jobRegistry))
+	mux.HandleFunc("/api/jobs", jobsHandler(jobRegistry))
+	mux.HandleFunc("/api/jobs/", jobsHandler(jobRegistry))
+	mux.HandleFunc("/api/indices", indicesHandler(pg, es))
+	mux.HandleFunc("/api/indices/migrate", migrateIndexHandler(ctx, pg, es, jobRegistry))
+	mux.HandleFunc("/api/indices/migrate/abort", abortMigrationHandler(ctx, pg, es, jobRegistry))
+	mux.HandleFunc("/api/reconcile", reconcileHandler(ctx, pg, es, jobRegistry))
+	mux.HandleFunc("/api/reconcile/", reconcileHandler(ctx, pg, es, jobRegistry))
+	mux.HandleFunc("/api/mappings", mappingsHandler(es))
+	mux.HandleFunc("/api/mappings/apply", mappingsHandler(es))
+
 	mux.HandleFunc("/api/add-user", func(w http.ResponseWriter, r *http.Request) {
 		skills, _ := json.Marshal([]string{"Go", "React"})
 		u := models.User{
@@ -152,11 +258,27 @@ func main() {
 		json.NewEncoder(w).Encode(map[string]any{"status": "updated", "id": u.ID})
 	})
 
-	log.Println("🧭 Admin API running on :8080")
-	if err := http.ListenAndServe(":8080", corsMiddleware.Handler(mux)); err != nil {
-		log.Fatalf("admin API listener failed: %v", err)
+	<-ctx.Done()
+	stop() // a second signal kills the process right away
+	log.Printf("🛑 shutdown requested, allowing up to %s", health.ShutdownTimeout())
+	readiness.Set(false, "shutting down")
+
+	// the worker is already draining; stop taking requests meanwhile
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), health.ShutdownTimeout())
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("❌ admin API shutdown: %v", err)
+	}
+	select {
+	case <-workerDone:
+	case <-shutdownCtx.Done():
+		log.Println("⚠️ sync worker did not finish draining before SHUTDOWN_TIMEOUT")
+	}
+	select {
+	case <-leaderDone:
+	case <-shutdownCtx.Done():
 	}
-	select {}
+	log.Println("👋 sync service stopped")
 
 	// log.Println("Worker running. Give it a moment to sync…")
 	// time.Sleep(3 * time.Second)
@@ -164,3 +286,20 @@ func main() {
 	// log.Println("🌍 Sync service initialized and DB ready.")
 	// log.Println(pg, es)
 }
+
+// loadSyncConfig registers the extra synced tables declared in SYNC_CONFIG,
+// see readme. It must run before the worker or a reindex starts.
+func loadSyncConfig(ctx context.Context, pg *gorm.DB, es *elasticsearch.Client) {
+	path := os.Getenv("SYNC_CONFIG")
+	if path == "" {
+		return
+	}
+	cfg, err := syncconfig.Load(path)
+	if err != nil {
+		log.Fatalf("❌ invalid sync config: %v", err)
+	}
+	if err := cfg.Apply(ctx, pg, es); err != nil {
+		log.Fatalf("❌ sync config rejected: %v", err)
+	}
+	log.Printf("✅ sync config loaded: %d extra entities", len(cfg.Entities))
+}
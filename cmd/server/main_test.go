@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDrainTimeout(t *testing.T) {
+	tests := []struct {
+		shutdown, want time.Duration
+	}{
+		{30 * time.Second, 25 * time.Second},
+		{10 * time.Second, 5 * time.Second},
+		{6 * time.Second, time.Second},
+		{5 * time.Second, time.Second},
+		{time.Second, time.Second},
+	}
+	for _, tt := range tests {
+		got := drainTimeout(tt.shutdown)
+		if got != tt.want {
+			t.Errorf("drainTimeout(%s) = %s, want %s", tt.shutdown, got, tt.want)
+		}
+		if tt.shutdown > shutdownReleaseMargin && got >= tt.shutdown {
+			t.Errorf("drainTimeout(%s) = %s leaves nothing for releasing claims", tt.shutdown, got)
+		}
+	}
+}
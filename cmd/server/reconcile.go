@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	elasticsearch "github.com/elastic/go-elasticsearch/v8"
+	"github.com/sirdesai22/sync-service/internal/db"
+	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/jobs"
+	"github.com/sirdesai22/sync-service/internal/leader"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"github.com/sirdesai22/sync-service/internal/workers"
+	"gorm.io/gorm"
+)
+
+// reconcileCommand implements `server reconcile [flags] [entity_type...]`.
+// With no entity types every registered type is checked. It exits non-zero
+// when any difference was found so it can gate a deploy or a cron alert.
+func reconcileCommand(args []string) {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	chunk := fs.Int("chunk", 500, "ids per page")
+	repair := fs.Bool("repair", false, "enqueue outbox events to fix every difference")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s reconcile [flags] [entity_type...]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pg := db.Connect()
+	db.Migrate(pg)
+	es := elastic.Connect()
+	loadSyncConfig(ctx, pg, es)
+
+	types := fs.Args()
+	if len(types) == 0 {
+		types = workers.EntityTypes()
+	}
+	r := &workers.Reconciler{DB: pg, ES: es}
+	dirty := false
+	for _, t := range types {
+		rep, err := r.Run(ctx, workers.ReconcileOptions{EntityType: t, ChunkSize: *chunk, Repair: *repair})
+		if err != nil {
+			log.Fatalf("❌ reconcile %s failed: %v", t, err)
+		}
+		if rep.Missing+rep.Orphaned+rep.Stale > 0 {
+			dirty = true
+		}
+	}
+	if dirty && !*repair {
+		os.Exit(1)
+	}
+}
+
+// startReconcileSchedule runs a full reconciliation every RECONCILE_INTERVAL
+// (a Go duration, e.g. "6h"); unset or "0" disables it. RECONCILE_REPAIR=true
+// lets the scheduled run enqueue repairs as well as report. Only the leader
+// runs it.
+func startReconcileSchedule(ctx context.Context, pg *gorm.DB, es *elasticsearch.Client, elector *leader.Elector) {
+	raw := os.Getenv("RECONCILE_INTERVAL")
+	if raw == "" || raw == "0" {
+		return
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval <= 0 {
+		log.Printf("⚠️ invalid RECONCILE_INTERVAL %q: scheduled reconciliation disabled", raw)
+		return
+	}
+	repair := os.Getenv("RECONCILE_REPAIR") == "true"
+	log.Printf("🔍 reconciling every %s (repair=%v)", interval, repair)
+	elector.WhileLeader(ctx, "scheduled reconciliation", func(ctx context.Context) {
+		(&workers.Reconciler{DB: pg, ES: es}).Schedule(ctx, interval, repair)
+	})
+}
+
+// reconcileHandler serves the admin API:
+//
+//	POST /api/reconcile          body: workers.ReconcileOptions -> starts a job
+//	GET  /api/reconcile          lists reconcile jobs
+//	GET  /api/reconcile/reports  latest report per entity type (?entity_type= for history)
+func reconcileHandler(ctx context.Context, pg *gorm.DB, es *elasticsearch.Client, registry *jobs.Registry) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		sub := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/reconcile"), "/")
+
+		switch {
+		case r.Method == http.MethodGet && sub == "":
+			json.NewEncoder(rw).Encode(registry.List("reconcile"))
+
+		case r.Method == http.MethodGet && sub == "reports":
+			if t := r.URL.Query().Get("entity_type"); t != "" {
+				var reports []models.ReconcileReport
+				pg.Where("entity_type = ?", t).Order("id desc").Limit(50).Find(&reports)
+				json.NewEncoder(rw).Encode(reports)
+				return
+			}
+			reports, err := workers.LatestReconcileReports(pg)
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(rw).Encode(reports)
+
+		case r.Method == http.MethodPost && sub == "":
+			var opts workers.ReconcileOptions
+			if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+				http.Error(rw, "invalid body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if _, err := workers.HandlerFor(opts.EntityType); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			j := registry.Start(ctx, "reconcile", opts, func(ctx context.Context, j *jobs.Job) (any, error) {
+				var last models.ReconcileReport
+				rc := &workers.Reconciler{DB: pg, ES: es}
+				rc.OnProgress = func(rep models.ReconcileReport) {
+					j.AddProcessed(rep.Scanned + rep.Documents - last.Scanned - last.Documents)
+					j.SetMessage(fmt.Sprintf("missing=%d orphaned=%d stale=%d repaired=%d unrepairable=%d", rep.Missing, rep.Orphaned, rep.Stale, rep.Repaired, rep.Unrepairable))
+					last = rep
+				}
+				return rc.Run(ctx, opts)
+			})
+			rw.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(rw).Encode(j.Snapshot())
+
+		default:
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	elasticsearch "github.com/elastic/go-elasticsearch/v8"
+	"github.com/sirdesai22/sync-service/internal/db"
+	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/jobs"
+	"github.com/sirdesai22/sync-service/internal/workers"
+	"gorm.io/gorm"
+)
+
+// reindexCommand implements `server reindex [flags] <entity_type>`: a
+// foreground full backfill that exits when done. Ctrl-C stops it after the
+// in-flight pages are acknowledged, and --resume picks up from there.
+func reindexCommand(args []string) {
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+	opts := workers.ReindexOptions{}
+	fs.IntVar(&opts.ChunkSize, "chunk", 500, "rows per keyset page")
+	fs.Float64Var(&opts.MaxRate, "rate", 0, "max documents per second (0 = unthrottled)")
+	fs.BoolVar(&opts.Resume, "resume", false, "continue from the stored checkpoint")
+	fs.StringVar(&opts.Index, "index", "", "target index (defaults to the entity's index)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s reindex [flags] <entity_type>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	opts.EntityType = fs.Arg(0)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pg := db.Connect()
+	db.Migrate(pg)
+	es := elastic.Connect()
+	loadSyncConfig(ctx, pg, es)
+
+	r := &workers.Reindexer{DB: pg, ES: es}
+	p, err := r.Run(ctx, opts)
+	if err != nil {
+		log.Fatalf("❌ reindex %s failed after %d documents (rerun with --resume): %v", opts.EntityType, p.Indexed, err)
+	}
+	log.Printf("✅ reindex %s complete: %d indexed, %d skipped, %d failed", opts.EntityType, p.Indexed, p.Skipped, p.Failed)
+}
+
+// reindexHandler serves the admin API:
+//
+//	POST /api/reindex       body: workers.ReindexOptions -> starts a job
+//	GET  /api/reindex       lists reindex jobs
+//	GET  /api/reindex/{id}  one job's progress
+//	DELETE /api/reindex/{id} cancels a running job
+func reindexHandler(ctx context.Context, pg *gorm.DB, es *elasticsearch.Client, registry *jobs.Registry) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/reindex"), "/")
+
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			json.NewEncoder(rw).Encode(registry.List("reindex"))
+
+		case r.Method == http.MethodGet:
+			j, ok := registry.Get(id)
+			if !ok {
+				http.Error(rw, "not found", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(rw).Encode(j.Snapshot())
+
+		case r.Method == http.MethodDelete && id != "":
+			if !registry.Cancel(id) {
+				http.Error(rw, "not found", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(rw).Encode(map[string]string{"status": "canceling"})
+
+		case r.Method == http.MethodPost && id == "":
+			var opts workers.ReindexOptions
+			if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+				http.Error(rw, "invalid body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if _, err := workers.HandlerFor(opts.EntityType); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			admit := func(running []jobs.Snapshot) error {
+				for _, s := range running {
+					if p, ok := s.Params.(workers.ReindexOptions); ok && p.EntityType == opts.EntityType {
+						return fmt.Errorf("reindex already running: %s", s.ID)
+					}
+				}
+				return nil
+			}
+			j, err := registry.StartIf(ctx, "reindex", opts, admit, func(ctx context.Context, j *jobs.Job) (any, error) {
+				// the reindexer reports cumulative progress; the job counts deltas
+				var last workers.ReindexProgress
+				rx := &workers.Reindexer{DB: pg, ES: es}
+				rx.OnProgress = func(p workers.ReindexProgress) {
+					j.AddProcessed(p.Indexed - last.Indexed)
+					j.AddFailed(p.Failed - last.Failed)
+					j.SetMessage(fmt.Sprintf("last_id=%s skipped=%d", p.LastID, p.Skipped))
+					last = p
+				}
+				return rx.Run(ctx, opts)
+			})
+			if err != nil {
+				http.Error(rw, err.Error(), http.StatusConflict)
+				return
+			}
+			rw.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(rw).Encode(j.Snapshot())
+
+		default:
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -14,9 +14,73 @@ func Migrate(db *gorm.DB) {
 		&models.Project{},
 		&models.Outbox{},
 		&models.DLQ{},
+		&models.DLQAttempt{},
+		&models.ReindexCheckpoint{},
+		&models.ReindexRequest{},
+		&models.IndexMigration{},
+		&models.ReconcileReport{},
+		&models.WorkerMember{},
+		&models.LeaderLease{},
 	)
 	if err != nil {
 		log.Fatalf("❌ migration failed: %v", err)
 	}
+	if err := migrateOutboxProcessed(db); err != nil {
+		log.Fatalf("❌ outbox status migration failed: %v", err)
+	}
+	if err := migrateDLQFingerprints(db); err != nil {
+		log.Fatalf("❌ DLQ fingerprint migration failed: %v", err)
+	}
+	if err := migrateReindexCheckpointKey(db); err != nil {
+		log.Fatalf("❌ reindex checkpoint migration failed: %v", err)
+	}
 	log.Println("✅ database migrated successfully")
 }
+
+// migrateOutboxProcessed carries the legacy boolean `processed` column over to
+// the lease-based `status` column, then drops it. Rows that were already
+// flipped to processed are treated as done; everything else stays pending.
+func migrateOutboxProcessed(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Outbox{}, "processed") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("UPDATE outboxes SET status = ? WHERE processed = true", models.OutboxDone).Error; err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&models.Outbox{}, "processed")
+	})
+}
+
+// migrateDLQFingerprints fills in the fingerprint of DLQ rows written before
+// errors were fingerprinted.
+func migrateDLQFingerprints(db *gorm.DB) error {
+	var rows []models.DLQ
+	return db.Select("id", "category", "error_msg").Where("fingerprint IS NULL OR fingerprint = ''").
+		FindInBatches(&rows, 500, func(tx *gorm.DB, _ int) error {
+			for _, r := range rows {
+				fp := models.ErrorFingerprint(r.Category, r.ErrorMsg)
+				if err := tx.Model(&models.DLQ{}).Where("id = ?", r.ID).Update("fingerprint", fp).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// migrateReindexCheckpointKey widens the reindex_checkpoints primary key from
+// entity_type to (entity_type, target_index), so a migration backfill and a
+// plain reindex of the same entity keep separate cursors. AutoMigrate adds
+// the column but never changes an existing primary key.
+func migrateReindexCheckpointKey(db *gorm.DB) error {
+	var n int64
+	err := db.Raw(`SELECT count(*) FROM information_schema.key_column_usage
+		WHERE table_name = 'reindex_checkpoints' AND constraint_name = 'reindex_checkpoints_pkey'
+		  AND column_name = 'target_index'`).Scan(&n).Error
+	if err != nil || n > 0 {
+		return err
+	}
+	return db.Exec(`ALTER TABLE reindex_checkpoints
+		DROP CONSTRAINT reindex_checkpoints_pkey,
+		ADD PRIMARY KEY (entity_type, target_index)`).Error
+}
@@ -1,18 +1,52 @@
 package db
 
 import (
+	"context"
 	"log"
 	"os"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirdesai22/sync-service/internal/health"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// Connect opens the GORM pool, waiting (see health.Wait) for Postgres to
+// accept connections for up to STARTUP_TIMEOUT.
 func Connect() *gorm.DB {
 	dsn := os.Getenv("POSTGRES_DSN")
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	var db *gorm.DB
+	err := health.Wait(context.Background(), "Postgres", health.StartupTimeout(), func(context.Context) error {
+		var err error
+		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		return err
+	})
 	if err != nil {
 		log.Fatalf("❌ failed to connect to Postgres: %v", err)
 	}
 	log.Println("✅ Connected to Postgres")
 	return db
 }
+
+// Ping checks that Postgres answers on the pool.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Listen opens a dedicated connection (outside the GORM pool) and issues
+// LISTEN on channel. The caller owns the connection and must close it.
+func Listen(ctx context.Context, channel string) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, os.Getenv("POSTGRES_DSN"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+	return conn, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	// "github.com/google/uuid"
 	"github.com/sirdesai22/sync-service/internal/models"
+	"github.com/sirdesai22/sync-service/internal/services"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
@@ -60,6 +61,17 @@ func Seed(db *gorm.DB) {
 			return err
 		}
 
+		// 4️⃣ Enqueue them so a fresh install starts with populated indices
+		if err := services.AddOutboxEvent(tx, "user", user.ID, "UPSERT", user); err != nil {
+			return err
+		}
+		if err := services.AddOutboxEvent(tx, "hackathon", hackathon.ID, "UPSERT", hackathon); err != nil {
+			return err
+		}
+		if err := services.AddOutboxEvent(tx, "project", project.ID, "UPSERT", project); err != nil {
+			return err
+		}
+
 		log.Println("🌱 Sample data inserted successfully.")
 		return nil
 	})
@@ -0,0 +1,121 @@
+// internal/elastic/alias.go
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+// ResolveAlias returns the indices alias points at, or nil when it does not exist.
+func ResolveAlias(ctx context.Context, c *es.Client, alias string) ([]string, error) {
+	res, err := c.Indices.GetAlias(c.Indices.GetAlias.WithName(alias), c.Indices.GetAlias.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("get alias %s: %w", alias, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get alias %s: %s", alias, res.String())
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("get alias %s: %w", alias, err)
+	}
+	indices := make([]string, 0, len(body))
+	for idx := range body {
+		indices = append(indices, idx)
+	}
+	sort.Strings(indices)
+	return indices, nil
+}
+
+// IndexVersion parses N out of <alias>_v<N>, returning 0 for other names.
+func IndexVersion(alias, index string) int {
+	v, err := strconv.Atoi(strings.TrimPrefix(index, alias+"_v"))
+	if err != nil || !strings.HasPrefix(index, alias+"_v") {
+		return 0
+	}
+	return v
+}
+
+// SwapAlias atomically moves alias from one index to another in a single
+// _aliases call, so readers never see the alias missing. from may be empty
+// when the alias is being created.
+func SwapAlias(ctx context.Context, c *es.Client, alias, from, to string) error {
+	var actions []map[string]any
+	if from != "" {
+		actions = append(actions, map[string]any{"remove": map[string]any{"index": from, "alias": alias}})
+	}
+	actions = append(actions, map[string]any{"add": map[string]any{"index": to, "alias": alias, "is_write_index": true}})
+
+	res, err := c.Indices.UpdateAliases(esutil.NewJSONReader(map[string]any{"actions": actions}), c.Indices.UpdateAliases.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("swap alias %s -> %s: %w", alias, to, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("swap alias %s -> %s: %s", alias, to, res.String())
+	}
+	return nil
+}
+
+// CountDocs refreshes index and returns its document count.
+func CountDocs(ctx context.Context, c *es.Client, index string) (int64, error) {
+	ref, err := c.Indices.Refresh(c.Indices.Refresh.WithIndex(index), c.Indices.Refresh.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("refresh %s: %w", index, err)
+	}
+	ref.Body.Close()
+
+	res, err := c.Count(c.Count.WithIndex(index), c.Count.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("count %s: %w", index, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return 0, fmt.Errorf("count %s: %s", index, res.String())
+	}
+	var body struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("count %s: %w", index, err)
+	}
+	return body.Count, nil
+}
+
+// DeleteIndexIfExists is DeleteIndex without the error for a missing index.
+func DeleteIndexIfExists(ctx context.Context, c *es.Client, index string) error {
+	res, err := c.Indices.Delete([]string{index}, c.Indices.Delete.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("delete index %s: %w", index, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("delete index %s: %s", index, res.String())
+	}
+	return nil
+}
+
+func DeleteIndex(ctx context.Context, c *es.Client, index string) error {
+	res, err := c.Indices.Delete([]string{index}, c.Indices.Delete.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("delete index %s: %w", index, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("delete index %s: %s", index, res.String())
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package elastic
+
+import "testing"
+
+func TestIndexVersion(t *testing.T) {
+	tests := []struct {
+		alias, index string
+		want         int
+	}{
+		{"users", "users_v1", 1},
+		{"users", "users_v12", 12},
+		{"users", "users", 0},
+		{"users", "users_vx", 0},
+		{"users", "users_v", 0},
+		{"users", "projects_v3", 0},
+		{"users", "old_users_v3", 0},
+		{"user", "users_v3", 0},
+	}
+	for _, tt := range tests {
+		if got := IndexVersion(tt.alias, tt.index); got != tt.want {
+			t.Errorf("IndexVersion(%q, %q) = %d, want %d", tt.alias, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestVersionedNameRoundTrip(t *testing.T) {
+	for _, v := range []int{1, 2, 10} {
+		name := VersionedName("projects", v)
+		if got := IndexVersion("projects", name); got != v {
+			t.Errorf("IndexVersion(%q) = %d, want %d", name, got, v)
+		}
+	}
+}
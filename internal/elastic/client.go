@@ -1,12 +1,20 @@
 package elastic
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"os"
+	"time"
 
 	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/sirdesai22/sync-service/internal/health"
 )
 
+// Connect creates the client and waits (see health.Wait) for the cluster to
+// report at least yellow health, for up to STARTUP_TIMEOUT.
 func Connect() *es.Client {
 	cfg := es.Config{
 		Addresses: []string{os.Getenv("ELASTIC_URL")},
@@ -15,6 +23,45 @@ func Connect() *es.Client {
 	if err != nil {
 		log.Fatalf("❌ failed to connect to Elasticsearch: %v", err)
 	}
+	if err := health.Wait(context.Background(), "Elasticsearch", health.StartupTimeout(), func(ctx context.Context) error {
+		return Ping(ctx, client)
+	}); err != nil {
+		log.Fatalf("❌ failed to connect to Elasticsearch: %v", err)
+	}
 	log.Println("✅ Connected to Elasticsearch")
 	return client
 }
+
+// Ping waits up to 5s for the cluster to reach at least yellow health.
+func Ping(ctx context.Context, c *es.Client) error {
+	status, err := clusterHealth(ctx, c,
+		c.Cluster.Health.WithWaitForStatus("yellow"),
+		c.Cluster.Health.WithTimeout(5*time.Second))
+	if err == nil && status == "red" {
+		err = fmt.Errorf("cluster health is red")
+	}
+	return err
+}
+
+// ClusterHealth returns the cluster status (green, yellow or red) right away.
+func ClusterHealth(ctx context.Context, c *es.Client) (string, error) {
+	return clusterHealth(ctx, c)
+}
+
+func clusterHealth(ctx context.Context, c *es.Client, opts ...func(*esapi.ClusterHealthRequest)) (string, error) {
+	res, err := c.Cluster.Health(append(opts, c.Cluster.Health.WithContext(ctx))...)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return "", fmt.Errorf("cluster health: %s", res.String())
+	}
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	return body.Status, nil
+}
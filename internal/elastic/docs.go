@@ -32,12 +32,31 @@ func BuildHackathonDoc(h models.Hackathon) ([]byte, error) {
 	return json.Marshal(HackathonDoc{h.Name, h.Location, tracks, h.StartAt, h.EndAt, h.UpdatedAt})
 }
 
+// ProjectOwner and ProjectHackathon are embedded in project docs so search
+// results can show them without extra lookups. They are nil when the
+// referenced row no longer exists.
+type ProjectOwner struct {
+	ID uuid.UUID `json:"id"`; Username string `json:"username"`; College string `json:"college"`
+}
+type ProjectHackathon struct {
+	ID uuid.UUID `json:"id"`; Name string `json:"name"`; Location string `json:"location"`; Tracks []string `json:"tracks"`
+}
+
 type ProjectDoc struct {
 	Name string `json:"name"`; Description string `json:"description"`
 	HackathonID uuid.UUID `json:"hackathon_id"`; OwnerID uuid.UUID `json:"owner_id"`
 	TeamMembers []string `json:"team_members"`; UpdatedAt time.Time `json:"updated_at"`
+	Owner *ProjectOwner `json:"owner"`; Hackathon *ProjectHackathon `json:"hackathon"`
 }
-func BuildProjectDoc(p models.Project) ([]byte, error) {
+func BuildProjectDoc(p models.Project, owner *models.User, h *models.Hackathon) ([]byte, error) {
 	var members []string; _ = json.Unmarshal(p.TeamMembers, &members)
-	return json.Marshal(ProjectDoc{p.Name, p.Description, p.HackathonID, p.OwnerID, members, p.UpdatedAt})
+	doc := ProjectDoc{Name: p.Name, Description: p.Description, HackathonID: p.HackathonID, OwnerID: p.OwnerID, TeamMembers: members, UpdatedAt: p.UpdatedAt}
+	if owner != nil {
+		doc.Owner = &ProjectOwner{owner.ID, owner.Username, owner.College}
+	}
+	if h != nil {
+		var tracks []string; _ = json.Unmarshal(h.Tracks, &tracks)
+		doc.Hackathon = &ProjectHackathon{h.ID, h.Name, h.Location, tracks}
+	}
+	return json.Marshal(doc)
 }
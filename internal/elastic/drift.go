@@ -0,0 +1,250 @@
+// internal/elastic/drift.go
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+// Kinds of mapping differences between a spec and the live index.
+const (
+	DriftAdditive = "additive" // declared but not live; fixable with put-mapping
+	DriftExtra    = "extra"    // live but no longer declared; harmless
+	DriftBreaking = "breaking" // incompatible; needs migrate-index
+)
+
+type Difference struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"`
+	Declared any    `json:"declared,omitempty"`
+	Live     any    `json:"live,omitempty"`
+}
+
+// Drift compares one alias's declared mapping with the mapping of the index it
+// currently points at.
+type Drift struct {
+	Alias       string       `json:"alias"`
+	Index       string       `json:"index"`
+	Differences []Difference `json:"differences"`
+	Applied     bool         `json:"applied"` // additive changes were put to the live index
+}
+
+func (d Drift) Breaking() bool { return d.count(DriftBreaking) > 0 }
+func (d Drift) Additive() bool { return d.count(DriftAdditive) > 0 }
+
+func (d Drift) count(kind string) int {
+	n := 0
+	for _, diff := range d.Differences {
+		if diff.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// CheckMappings diffs every registered spec against the cluster. With apply
+// set, additive differences are put to the live indices right away.
+func CheckMappings(ctx context.Context, c *es.Client, apply bool) ([]Drift, error) {
+	var out []Drift
+	for _, s := range Specs() {
+		d, err := DetectDrift(ctx, c, s)
+		if err != nil {
+			return out, err
+		}
+		if apply && d.Additive() {
+			if err := ApplyAdditive(ctx, c, s, d); err != nil {
+				return out, err
+			}
+			d.Applied = true
+		}
+		for _, diff := range d.Differences {
+			log.Printf("🧭 mapping drift %s (%s) %s: %s declared=%v live=%v", d.Alias, d.Index, diff.Kind, diff.Path, diff.Declared, diff.Live)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// DetectDrift fetches GET <alias>/_mapping and classifies every difference
+// with the mapping declared in s.
+func DetectDrift(ctx context.Context, c *es.Client, s IndexSpec) (Drift, error) {
+	d := Drift{Alias: s.Alias}
+	declared, err := declaredMapping(s)
+	if err != nil {
+		return d, err
+	}
+
+	res, err := c.Indices.GetMapping(c.Indices.GetMapping.WithIndex(s.Alias), c.Indices.GetMapping.WithContext(ctx))
+	if err != nil {
+		return d, fmt.Errorf("get mapping %s: %w", s.Alias, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return d, fmt.Errorf("get mapping %s: %s", s.Alias, res.String())
+	}
+	var body map[string]struct {
+		Mappings map[string]any `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return d, fmt.Errorf("get mapping %s: %w", s.Alias, err)
+	}
+	if len(body) != 1 {
+		return d, fmt.Errorf("alias %s resolves to %d indices", s.Alias, len(body))
+	}
+	var live map[string]any
+	for idx, m := range body {
+		d.Index, live = idx, m.Mappings
+	}
+
+	if dv, lv := declared["dynamic"], live["dynamic"]; dv != nil && fmt.Sprint(dv) != fmt.Sprint(lv) {
+		// dynamic is updatable in place, so it counts as additive
+		d.Differences = append(d.Differences, Difference{Path: "dynamic", Kind: DriftAdditive, Declared: dv, Live: lv})
+	}
+	d.Differences = append(d.Differences, diffProperties("", props(declared, "properties"), props(live, "properties"))...)
+	return d, nil
+}
+
+// ApplyAdditive puts the declared-but-missing fields (and dynamic setting) of
+// d onto the live index. Breaking differences are left alone.
+func ApplyAdditive(ctx context.Context, c *es.Client, s IndexSpec, d Drift) error {
+	declared, err := declaredMapping(s)
+	if err != nil {
+		return err
+	}
+	patch := map[string]any{}
+	for _, diff := range d.Differences {
+		if diff.Kind != DriftAdditive {
+			continue
+		}
+		if diff.Path == "dynamic" {
+			patch["dynamic"] = diff.Declared
+			continue
+		}
+		setPath(patch, declared, strings.Split(diff.Path, "."))
+	}
+	res, err := c.Indices.PutMapping([]string{d.Index}, esutil.NewJSONReader(patch), c.Indices.PutMapping.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("put mapping %s: %w", d.Index, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("put mapping %s: %s", d.Index, res.String())
+	}
+	log.Printf("✅ applied additive mapping changes to %s", d.Index)
+	return nil
+}
+
+func declaredMapping(s IndexSpec) (map[string]any, error) {
+	var body struct {
+		Mappings map[string]any `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(s.Body), &body); err != nil {
+		return nil, fmt.Errorf("spec %s: %w", s.Alias, err)
+	}
+	if body.Mappings == nil {
+		body.Mappings = map[string]any{}
+	}
+	return body.Mappings, nil
+}
+
+func props(m map[string]any, key string) map[string]any {
+	p, _ := m[key].(map[string]any)
+	return p
+}
+
+// diffProperties walks properties (and multi-field "fields") recursively.
+// Paths use dots, with multi-fields as <field>.fields.<name>.
+func diffProperties(prefix string, declared, live map[string]any) []Difference {
+	var out []Difference
+	for _, name := range sortedKeys(declared, live) {
+		path := prefix + name
+		dv, dok := declared[name].(map[string]any)
+		lv, lok := live[name].(map[string]any)
+		switch {
+		case dok && !lok:
+			out = append(out, Difference{Path: path, Kind: DriftAdditive, Declared: dv})
+		case !dok && lok:
+			out = append(out, Difference{Path: path, Kind: DriftExtra, Live: lv})
+		default:
+			if fieldType(dv) != fieldType(lv) {
+				out = append(out, Difference{Path: path, Kind: DriftBreaking, Declared: fieldType(dv), Live: fieldType(lv)})
+				continue
+			}
+			for k, want := range dv {
+				if k == "type" || k == "properties" || k == "fields" {
+					continue
+				}
+				if !reflect.DeepEqual(want, lv[k]) {
+					out = append(out, Difference{Path: path + "." + k, Kind: DriftBreaking, Declared: want, Live: lv[k]})
+				}
+			}
+			out = append(out, diffProperties(path+".", props(dv, "properties"), props(lv, "properties"))...)
+			out = append(out, diffProperties(path+".fields.", props(dv, "fields"), props(lv, "fields"))...)
+		}
+	}
+	return out
+}
+
+// fieldType defaults to "object" for fields declared only by their properties.
+func fieldType(m map[string]any) string {
+	if t, ok := m["type"].(string); ok {
+		return t
+	}
+	return "object"
+}
+
+// setPath copies the declared definition at path into patch, creating the
+// intermediate properties/fields objects put-mapping expects.
+func setPath(patch, declared map[string]any, path []string) {
+	dst, src := patch, declared
+	for i := 0; i < len(path); i++ {
+		container := "properties"
+		if path[i] == "fields" && i+1 < len(path) {
+			container, i = "fields", i+1
+		}
+		name := path[i]
+		srcChildren := props(src, container)
+		dstChildren, ok := dst[container].(map[string]any)
+		if !ok {
+			dstChildren = map[string]any{}
+			dst[container] = dstChildren
+		}
+		if i == len(path)-1 {
+			dstChildren[name] = srcChildren[name]
+			return
+		}
+		srcNode, _ := srcChildren[name].(map[string]any)
+		dstNode, ok := dstChildren[name].(map[string]any)
+		if !ok {
+			dstNode = map[string]any{}
+			if t, ok := srcNode["type"]; ok {
+				dstNode["type"] = t
+			}
+			dstChildren[name] = dstNode
+		}
+		dst, src = dstNode, srcNode
+	}
+}
+
+func sortedKeys(a, b map[string]any) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range []map[string]any{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
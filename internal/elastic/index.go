@@ -4,40 +4,142 @@ package elastic
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"sort"
+	"sync"
 	es "github.com/elastic/go-elasticsearch/v8"
 )
 
+// Aliases the service reads and writes through. Each points at exactly one
+// versioned index (<alias>_v<N>, see IndexSpec) marked as its write index.
 const (
-	IdxUsers     = "users_v1"
-	IdxHackathons= "hackathons_v1"
-	IdxProjects  = "projects_v1"
+	IdxUsers     = "users"
+	IdxHackathons= "hackathons"
+	IdxProjects  = "projects"
 )
 
-func EnsureIndexes(ctx context.Context, c *es.Client) error {
-	mapping := `{"settings":{"number_of_shards":1},"mappings":{"dynamic":"strict","properties":{
+// IndexSpec declares the mapping an alias should have. Changing a mapping means
+// bumping Version along with Body and running `server migrate-index <alias>`,
+// which builds the new index next to the old one and swaps the alias.
+type IndexSpec struct {
+	Alias   string
+	Version int
+	Body    string // create-index body (settings + mappings)
+}
+
+func (s IndexSpec) IndexName() string { return VersionedName(s.Alias, s.Version) }
+
+func VersionedName(alias string, version int) string { return fmt.Sprintf("%s_v%d", alias, version) }
+
+var (
+	specsMu sync.RWMutex
+	specs   = map[string]IndexSpec{}
+)
+
+func init() {
+	for _, s := range []IndexSpec{
+		{IdxUsers, 1, `{"settings":{"number_of_shards":1},"mappings":{"dynamic":"strict","properties":{
 		"username":{"type":"keyword"},"email":{"type":"keyword"},"skills":{"type":"keyword"},
 		"college":{"type":"text"},"updated_at":{"type":"date"}
-	}}}`
-	if err := ensure(ctx, c, IdxUsers, mapping); err != nil { return err }
-
-	mapping = `{"settings":{"number_of_shards":1},"mappings":{"dynamic":"strict","properties":{
+	}}}`},
+		{IdxHackathons, 1, `{"settings":{"number_of_shards":1},"mappings":{"dynamic":"strict","properties":{
 		"name":{"type":"text"},"location":{"type":"keyword"},"tracks":{"type":"keyword"},
 		"start_at":{"type":"date"},"end_at":{"type":"date"},"updated_at":{"type":"date"}
-	}}}`
-	if err := ensure(ctx, c, IdxHackathons, mapping); err != nil { return err }
-
-	mapping = `{"settings":{"number_of_shards":1},"mappings":{"dynamic":"strict","properties":{
+	}}}`},
+		// owner/hackathon were added in place: new fields are additive, so an
+		// existing index gets them from ensure's put-mapping
+		{IdxProjects, 1, `{"settings":{"number_of_shards":1},"mappings":{"dynamic":"strict","properties":{
 		"name":{"type":"text"},"description":{"type":"text"},"hackathon_id":{"type":"keyword"},
-		"owner_id":{"type":"keyword"},"team_members":{"type":"keyword"},"updated_at":{"type":"date"}
-	}}}`
-	return ensure(ctx, c, IdxProjects, mapping)
+		"owner_id":{"type":"keyword"},"team_members":{"type":"keyword"},"updated_at":{"type":"date"},
+		"owner":{"properties":{"id":{"type":"keyword"},"username":{"type":"keyword"},"college":{"type":"text"}}},
+		"hackathon":{"properties":{"id":{"type":"keyword"},"name":{"type":"text","fields":{"raw":{"type":"keyword"}}},
+			"location":{"type":"keyword"},"tracks":{"type":"keyword"}}}
+	}}}`},
+	} {
+		if err := RegisterIndex(s); err != nil { panic(err) }
+	}
+}
+
+// RegisterIndex declares an aliased index managed by EnsureIndexes and the
+// migration flow, e.g. for entities from the sync config file.
+func RegisterIndex(s IndexSpec) error {
+	if s.Alias == "" || s.Version < 1 { return fmt.Errorf("index spec needs an alias and a version >= 1, got %q v%d", s.Alias, s.Version) }
+	specsMu.Lock(); defer specsMu.Unlock()
+	if _, dup := specs[s.Alias]; dup { return fmt.Errorf("index alias %q registered twice", s.Alias) }
+	specs[s.Alias] = s
+	return nil
+}
+
+func Spec(alias string) (IndexSpec, bool) {
+	specsMu.RLock(); defer specsMu.RUnlock()
+	s, ok := specs[alias]
+	return s, ok
+}
+
+// Specs returns every registered spec ordered by alias.
+func Specs() []IndexSpec {
+	specsMu.RLock(); defer specsMu.RUnlock()
+	out := make([]IndexSpec, 0, len(specs))
+	for _, s := range specs { out = append(out, s) }
+	sort.Slice(out, func(i, j int) bool { return out[i].Alias < out[j].Alias })
+	return out
+}
+
+// IsManagedAlias reports whether name is an alias declared with RegisterIndex.
+func IsManagedAlias(name string) bool { _, ok := Spec(name); return ok }
+
+// EnsureIndexes makes sure every registered alias exists.
+func EnsureIndexes(ctx context.Context, c *es.Client) error {
+	for _, s := range Specs() {
+		if err := EnsureAlias(ctx, c, s); err != nil { return err }
+	}
+	return nil
+}
+
+// EnsureAlias attaches s.Alias to the spec's versioned index, creating that
+// index first when needed. An alias that already exists is left alone even
+// if it points at an older version; that is what migrate-index is for.
+func EnsureAlias(ctx context.Context, c *es.Client, s IndexSpec) error {
+	current, err := ResolveAlias(ctx, c, s.Alias)
+	if err != nil { return err }
+	if len(current) > 0 {
+		if v := IndexVersion(s.Alias, current[0]); v < s.Version {
+			log.Printf("⚠️ alias %s -> %s is behind declared v%d; run `migrate-index %s`", s.Alias, current[0], s.Version, s.Alias)
+		}
+		return nil
+	}
+	if err := ensure(ctx, c, s.IndexName(), s.Body); err != nil { return err }
+	return SwapAlias(ctx, c, s.Alias, "", s.IndexName())
 }
 
 func ensure(ctx context.Context, c *es.Client, index, body string) error {
-	exists, _ := c.Indices.Exists([]string{index})
-	if exists.StatusCode == 200 { return nil }
-	_, err := c.Indices.Create(index, c.Indices.Create.WithBody(bytes.NewBufferString(body)), c.Indices.Create.WithContext(ctx))
+	exists, err := c.Indices.Exists([]string{index}, c.Indices.Exists.WithContext(ctx))
+	if err != nil { return fmt.Errorf("check index %s: %w", index, err) }
+	exists.Body.Close()
+	if exists.StatusCode == 200 { return putMapping(ctx, c, index, body) }
+	return CreateIndex(ctx, c, index, body)
+}
+
+// putMapping adds the fields declared in body that an existing index lacks.
+// Elasticsearch merges new fields and refuses changes to existing ones; a
+// refusal is only logged, since the index keeps serving its current mapping.
+func putMapping(ctx context.Context, c *es.Client, index, body string) error {
+	var b struct{ Mappings json.RawMessage `json:"mappings"` }
+	if err := json.Unmarshal([]byte(body), &b); err != nil { return fmt.Errorf("mapping for %s: %w", index, err) }
+	if len(b.Mappings) == 0 { return nil }
+	res, err := c.Indices.PutMapping([]string{index}, bytes.NewReader(b.Mappings), c.Indices.PutMapping.WithContext(ctx))
+	if err != nil { return fmt.Errorf("put mapping %s: %w", index, err) }
+	defer res.Body.Close()
+	if res.IsError() { log.Printf("⚠️ mapping of %s not updated: %s", index, res.String()) }
+	return nil
+}
+
+func CreateIndex(ctx context.Context, c *es.Client, index, body string) error {
+	res, err := c.Indices.Create(index, c.Indices.Create.WithBody(bytes.NewBufferString(body)), c.Indices.Create.WithContext(ctx))
 	if err != nil { return fmt.Errorf("create index %s: %w", index, err) }
+	defer res.Body.Close()
+	if res.IsError() { return fmt.Errorf("create index %s: %s", index, res.String()) }
 	return nil
 }
@@ -0,0 +1,120 @@
+// internal/elastic/scan.go
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+// MultiGet fetches the _source of ids from index, keyed by id. Ids that are
+// not found are absent from the result.
+func MultiGet(ctx context.Context, c *es.Client, index string, ids []string) (map[string]json.RawMessage, error) {
+	res, err := c.Mget(esutil.NewJSONReader(map[string]any{"ids": ids}), c.Mget.WithIndex(index), c.Mget.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("mget %s: %w", index, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("mget %s: %s", index, res.String())
+	}
+
+	var body struct {
+		Docs []struct {
+			ID     string          `json:"_id"`
+			Found  bool            `json:"found"`
+			Source json.RawMessage `json:"_source"`
+		} `json:"docs"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("mget %s: %w", index, err)
+	}
+	out := make(map[string]json.RawMessage, len(body.Docs))
+	for _, d := range body.Docs {
+		if d.Found {
+			out[d.ID] = d.Source
+		}
+	}
+	return out, nil
+}
+
+// ScanIDs pages through every document id in index using a point in time and
+// search_after, calling fn once per page. The PIT gives a consistent view
+// even while the sync worker keeps writing.
+func ScanIDs(ctx context.Context, c *es.Client, index string, pageSize int, fn func(ids []string) error) error {
+	const keepAlive = "2m"
+	res, err := c.OpenPointInTime([]string{index}, keepAlive, c.OpenPointInTime.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("open pit %s: %w", index, err)
+	}
+	var pit struct {
+		ID string `json:"id"`
+	}
+	decErr := json.NewDecoder(res.Body).Decode(&pit)
+	res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("open pit %s: %s", index, res.String())
+	}
+	if decErr != nil {
+		return fmt.Errorf("open pit %s: %w", index, decErr)
+	}
+	defer func() {
+		// use a fresh context: the PIT should be released even when ctx is done
+		r, err := c.ClosePointInTime(c.ClosePointInTime.WithBody(esutil.NewJSONReader(map[string]string{"id": pit.ID})))
+		if err == nil {
+			r.Body.Close()
+		}
+	}()
+
+	var after []any
+	for {
+		query := map[string]any{
+			"size": pageSize, "_source": false, "track_total_hits": false,
+			"pit":  map[string]string{"id": pit.ID, "keep_alive": keepAlive},
+			"sort": []any{map[string]string{"_shard_doc": "asc"}},
+		}
+		if after != nil {
+			query["search_after"] = after
+		}
+		res, err := c.Search(c.Search.WithBody(esutil.NewJSONReader(query)), c.Search.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("scan %s: %w", index, err)
+		}
+		var body struct {
+			PitID string `json:"pit_id"`
+			Hits  struct {
+				Hits []struct {
+					ID   string `json:"_id"`
+					Sort []any  `json:"sort"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+		decErr := json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+		if res.IsError() {
+			return fmt.Errorf("scan %s: %s", index, res.String())
+		}
+		if decErr != nil {
+			return fmt.Errorf("scan %s: %w", index, decErr)
+		}
+
+		hits := body.Hits.Hits
+		if len(hits) == 0 {
+			return nil
+		}
+		if body.PitID != "" {
+			pit.ID = body.PitID
+		}
+		ids := make([]string, len(hits))
+		for i, h := range hits {
+			ids[i] = h.ID
+		}
+		if err := fn(ids); err != nil {
+			return err
+		}
+		after = hits[len(hits)-1].Sort
+	}
+}
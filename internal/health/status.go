@@ -0,0 +1,29 @@
+// internal/health/status.go
+// this file tracks whether the service has finished starting up
+package health
+
+import "sync"
+
+// Readiness is false until startup has verified every dependency and started
+// the sync worker; Reason says why it is not ready.
+type Readiness struct {
+	mu     sync.Mutex
+	ready  bool
+	reason string
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{reason: "starting"}
+}
+
+func (r *Readiness) Set(ready bool, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ready, r.reason = ready, reason
+}
+
+func (r *Readiness) Get() (bool, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ready, r.reason
+}
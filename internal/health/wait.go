@@ -0,0 +1,61 @@
+// internal/health/wait.go
+// this file waits for the service's dependencies to come up and bounds how long startup and shutdown may take
+package health
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	defaultStartupTimeout  = 2 * time.Minute
+	defaultShutdownTimeout = 25 * time.Second
+	waitInitialBackoff     = 500 * time.Millisecond
+	waitMaxBackoff         = 10 * time.Second
+)
+
+// StartupTimeout is how long startup waits for a dependency, from
+// STARTUP_TIMEOUT (a Go duration), default 2m.
+func StartupTimeout() time.Duration { return durationEnv("STARTUP_TIMEOUT", defaultStartupTimeout) }
+
+// ShutdownTimeout is how long shutdown may take in total, from
+// SHUTDOWN_TIMEOUT, default 25s (inside the usual 30s grace period).
+func ShutdownTimeout() time.Duration { return durationEnv("SHUTDOWN_TIMEOUT", defaultShutdownTimeout) }
+
+func durationEnv(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("⚠️ invalid %s %q, using %s", key, v, def)
+	}
+	return def
+}
+
+// Wait calls check until it succeeds, backing off exponentially between
+// attempts, and gives up after timeout.
+func Wait(ctx context.Context, name string, timeout time.Duration, check func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := waitInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := check(ctx)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("✅ %s is up after %d attempts", name, attempt)
+			}
+			return nil
+		}
+		log.Printf("⏳ waiting for %s (attempt %d, retrying in %s): %v", name, attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not ready after %s: %w", name, timeout, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, waitMaxBackoff)
+	}
+}
@@ -0,0 +1,26 @@
+package health
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", defaultShutdownTimeout},
+		{"45s", 45 * time.Second},
+		{"2m", 2 * time.Minute},
+		{"0", defaultShutdownTimeout},
+		{"-5s", defaultShutdownTimeout},
+		{"soon", defaultShutdownTimeout},
+	}
+	for _, tt := range tests {
+		t.Setenv("SHUTDOWN_TIMEOUT", tt.env)
+		if got := ShutdownTimeout(); got != tt.want {
+			t.Errorf("SHUTDOWN_TIMEOUT=%q: ShutdownTimeout() = %s, want %s", tt.env, got, tt.want)
+		}
+	}
+}
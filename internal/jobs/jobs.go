@@ -0,0 +1,181 @@
+// internal/jobs/jobs.go
+// this file tracks long-running admin jobs (reindexing, bulk DLQ operations) so the API can report progress
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	Running   = "running"
+	Succeeded = "succeeded"
+	Failed    = "failed"
+	Canceled  = "canceled"
+)
+
+// Job is one asynchronous admin job. Counters are updated by the job function
+// while it runs; read them through Snapshot.
+type Job struct {
+	ID     string
+	Kind   string
+	Params any
+
+	total     atomic.Int64
+	processed atomic.Int64
+	failed    atomic.Int64
+
+	mu         sync.Mutex
+	state      string
+	message    string
+	err        string
+	result     any
+	startedAt  time.Time
+	finishedAt *time.Time
+	cancel     context.CancelFunc
+}
+
+// Snapshot is the JSON view of a job.
+type Snapshot struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Params     any        `json:"params,omitempty"`
+	State      string     `json:"state"`
+	Total      int64      `json:"total"`
+	Processed  int64      `json:"processed"`
+	Failed     int64      `json:"failed"`
+	Message    string     `json:"message,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Result     any        `json:"result,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (j *Job) SetTotal(n int64)      { j.total.Store(n) }
+func (j *Job) AddProcessed(n int64)  { j.processed.Add(n) }
+func (j *Job) AddFailed(n int64)     { j.failed.Add(n) }
+func (j *Job) SetMessage(msg string) { j.mu.Lock(); j.message = msg; j.mu.Unlock() }
+
+func (j *Job) Snapshot() Snapshot {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return Snapshot{
+		ID: j.ID, Kind: j.Kind, Params: j.Params, State: j.state,
+		Total: j.total.Load(), Processed: j.processed.Load(), Failed: j.failed.Load(),
+		Message: j.message, Error: j.err, Result: j.result,
+		StartedAt: j.startedAt, FinishedAt: j.finishedAt,
+	}
+}
+
+// Registry keeps jobs of this process in memory. Finished jobs are kept for
+// inspection until the process restarts.
+type Registry struct {
+	mu   sync.Mutex
+	seq  int
+	jobs map[string]*Job
+}
+
+func NewRegistry() *Registry {
+	return &Registry{jobs: map[string]*Job{}}
+}
+
+// Start runs fn in its own goroutine under a context derived from parent and
+// returns the job immediately. fn's return value becomes the job result.
+func (r *Registry) Start(parent context.Context, kind string, params any, fn func(ctx context.Context, j *Job) (any, error)) *Job {
+	j, _ := r.StartIf(parent, kind, params, nil, fn)
+	return j
+}
+
+// StartIf is Start guarded by admit, which sees the running jobs of kind and
+// returns an error to refuse the new one. Checking and registering happen
+// under the registry lock, so concurrent callers cannot both slip past a cap.
+func (r *Registry) StartIf(parent context.Context, kind string, params any, admit func(running []Snapshot) error, fn func(ctx context.Context, j *Job) (any, error)) (*Job, error) {
+	r.mu.Lock()
+	if admit != nil {
+		var running []Snapshot
+		for _, j := range r.jobs {
+			if s := j.Snapshot(); s.Kind == kind && s.State == Running {
+				running = append(running, s)
+			}
+		}
+		if err := admit(running); err != nil {
+			r.mu.Unlock()
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithCancel(parent)
+	r.seq++
+	j := &Job{
+		ID:        fmt.Sprintf("%s-%d", kind, r.seq),
+		Kind:      kind,
+		Params:    params,
+		state:     Running,
+		startedAt: time.Now(),
+		cancel:    cancel,
+	}
+	r.jobs[j.ID] = j
+	r.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		result, err := fn(ctx, j)
+		now := time.Now()
+		j.mu.Lock()
+		j.result = result
+		j.finishedAt = &now
+		switch {
+		case err == nil:
+			j.state = Succeeded
+		case ctx.Err() != nil:
+			j.state = Canceled
+			j.err = err.Error()
+		default:
+			j.state = Failed
+			j.err = err.Error()
+		}
+		state := j.state
+		j.mu.Unlock()
+		log.Printf("🗂️ job %s %s in %s", j.ID, state, now.Sub(j.startedAt).Round(time.Millisecond))
+	}()
+	return j, nil
+}
+
+func (r *Registry) Get(id string) (*Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[id]
+	return j, ok
+}
+
+// Cancel stops a running job; it reports false for unknown ids.
+func (r *Registry) Cancel(id string) bool {
+	j, ok := r.Get(id)
+	if ok {
+		j.cancel()
+	}
+	return ok
+}
+
+// List returns snapshots of all jobs of kind (all kinds when empty), newest first.
+func (r *Registry) List(kind string) []Snapshot {
+	r.mu.Lock()
+	all := make([]*Job, 0, len(r.jobs))
+	for _, j := range r.jobs {
+		if kind == "" || j.Kind == kind {
+			all = append(all, j)
+		}
+	}
+	r.mu.Unlock()
+
+	out := make([]Snapshot, 0, len(all))
+	for _, j := range all {
+		out = append(out, j.Snapshot())
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].StartedAt.After(out[b].StartedAt) })
+	return out
+}
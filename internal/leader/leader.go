@@ -0,0 +1,175 @@
+// internal/leader/leader.go
+// this file elects one instance to run singleton background jobs, using a lease row in Postgres
+package leader
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirdesai22/sync-service/internal/metrics"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultTTL is how long a lease outlives its last renewal. The holder renews
+// every TTL/3, so it survives two failed renewals.
+const DefaultTTL = 15 * time.Second
+
+var logger = log.New(os.Stdout, "[LEADER] ", log.LstdFlags)
+
+// Elector campaigns for the lease Name on behalf of instance ID. Whichever
+// instance renews the row before it expires stays leader; when it stops (or
+// dies) another takes the row over once it has expired.
+type Elector struct {
+	DB   *gorm.DB
+	Name string
+	ID   string
+	TTL  time.Duration
+
+	leader atomic.Bool
+
+	mu        sync.Mutex
+	callbacks []func(leader bool)
+}
+
+func New(db *gorm.DB, name, id string) *Elector {
+	metrics.Leader.WithLabelValues(name).Set(0)
+	return &Elector{DB: db, Name: name, ID: id, TTL: DefaultTTL}
+}
+
+// IsLeader reports whether this instance currently holds the lease. A nil
+// Elector is always leader, so single-process tools need no election.
+func (e *Elector) IsLeader() bool {
+	return e == nil || e.leader.Load()
+}
+
+// OnChange registers fn to be called whenever leadership is gained or lost,
+// and right away if it is already held. fn runs synchronously with the
+// campaign loop and must not block.
+func (e *Elector) OnChange(fn func(leader bool)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.callbacks = append(e.callbacks, fn)
+	if e.leader.Load() {
+		fn(true)
+	}
+}
+
+// WhileLeader runs job in its own goroutine each time this instance becomes
+// leader, and cancels its context when leadership is lost or ctx is done.
+func (e *Elector) WhileLeader(ctx context.Context, name string, job func(ctx context.Context)) {
+	var cancel context.CancelFunc
+	e.OnChange(func(leader bool) {
+		if cancel != nil {
+			cancel()
+			cancel = nil
+			logger.Printf("⏹️ stopped %s", name)
+		}
+		if leader && ctx.Err() == nil {
+			var jobCtx context.Context
+			jobCtx, cancel = context.WithCancel(ctx)
+			logger.Printf("▶️ starting %s", name)
+			go job(jobCtx)
+		}
+	})
+}
+
+// Run campaigns until ctx is done, then gives the lease up so another
+// instance can take over without waiting for it to expire.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.TTL / 3)
+	defer ticker.Stop()
+
+	var validUntil time.Time
+	for {
+		start := time.Now()
+		held, err := e.campaign(ctx)
+		switch {
+		case err == nil:
+			if held {
+				validUntil = start.Add(e.TTL)
+			}
+			e.set(held)
+		case ctx.Err() != nil:
+		case time.Now().After(validUntil):
+			// can't renew and the lease may have gone to someone else by now
+			logger.Printf("❌ campaign for %s failed: %v", e.Name, err)
+			e.set(false)
+		default:
+			logger.Printf("⚠️ renewing %s failed, lease still valid for %s: %v", e.Name, time.Until(validUntil).Round(time.Second), err)
+		}
+
+		select {
+		case <-ctx.Done():
+			e.release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign takes the lease if it is free or expired, or renews it if this
+// instance holds it, and reports whether this instance holds it now.
+func (e *Elector) campaign(ctx context.Context) (bool, error) {
+	res := e.DB.WithContext(ctx).Exec(`
+		INSERT INTO leader_leases (name, holder, acquired_at, renewed_at, expires_at)
+		VALUES (?, ?, now(), now(), now() + make_interval(secs => ?))
+		ON CONFLICT (name) DO UPDATE SET
+		  holder = EXCLUDED.holder,
+		  acquired_at = CASE WHEN leader_leases.holder = EXCLUDED.holder
+		                     THEN leader_leases.acquired_at ELSE now() END,
+		  renewed_at = now(),
+		  expires_at = EXCLUDED.expires_at
+		WHERE leader_leases.holder = EXCLUDED.holder OR leader_leases.expires_at < now()`,
+		e.Name, e.ID, e.TTL.Seconds())
+	return res.RowsAffected == 1, res.Error
+}
+
+// release steps down and expires the lease if this instance still holds it.
+func (e *Elector) release() {
+	wasLeader := e.leader.Load()
+	e.set(false)
+	if !wasLeader {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := e.DB.WithContext(ctx).Model(&models.LeaderLease{}).
+		Where("name = ? AND holder = ?", e.Name, e.ID).
+		Update("expires_at", gorm.Expr("now()")).Error
+	if err != nil {
+		logger.Printf("❌ failed to release %s: %v", e.Name, err)
+		return
+	}
+	logger.Printf("👋 released %s", e.Name)
+}
+
+func (e *Elector) set(leader bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.leader.Load() == leader {
+		return
+	}
+	e.leader.Store(leader)
+	if leader {
+		metrics.Leader.WithLabelValues(e.Name).Set(1)
+		logger.Printf("👑 %s is now leader for %s", e.ID, e.Name)
+	} else {
+		metrics.Leader.WithLabelValues(e.Name).Set(0)
+		logger.Printf("%s is no longer leader for %s", e.ID, e.Name)
+	}
+	for _, fn := range e.callbacks {
+		fn(leader)
+	}
+}
+
+// Current returns the lease row as it stands, whoever holds it.
+func (e *Elector) Current(ctx context.Context) (models.LeaderLease, error) {
+	var l models.LeaderLease
+	err := e.DB.WithContext(ctx).Where("name = ?", e.Name).Take(&l).Error
+	return l, err
+}
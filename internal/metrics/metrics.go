@@ -12,8 +12,43 @@ var (
 	DLQEvents = prometheus.NewCounter(
 		prometheus.CounterOpts{Name: "sync_dlq_total", Help: "Total events inserted into DLQ"},
 	)
+	VersionConflicts = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "sync_version_conflicts_total", Help: "Total events skipped because the indexed document was already newer"},
+	)
+	CoalescedEvents = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "sync_coalesced_total", Help: "Total outbox events folded into another event for the same entity"},
+	)
+	SyncErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "sync_errors_total", Help: "Total sync failures by error category"},
+		[]string{"category"},
+	)
+	TransientRetries = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "sync_transient_retries_total", Help: "Total transient failures handed back to the outbox instead of the DLQ"},
+	)
+	DLQRetries = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "sync_dlq_retries_total", Help: "Total automatic DLQ retry attempts"},
+	)
+	DLQExhausted = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "sync_dlq_exhausted_total", Help: "Total DLQ rows marked permanently failed after their last retry"},
+	)
+	OwnedPartitions = prometheus.NewGauge(
+		prometheus.GaugeOpts{Name: "sync_owned_partitions", Help: "Outbox partitions currently owned by this instance"},
+	)
+	WorkerMembers = prometheus.NewGauge(
+		prometheus.GaugeOpts{Name: "sync_worker_members", Help: "Live sync worker instances sharing the outbox partitions"},
+	)
+	Leader = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{Name: "sync_leader", Help: "1 while this instance holds the named leader lease, 0 otherwise"},
+		[]string{"lease"},
+	)
+	Rebalances = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "sync_rebalances_total", Help: "Total changes of the partitions owned by this instance"},
+	)
+	CascadeTruncated = prometheus.NewCounter(
+		prometheus.CounterOpts{Name: "sync_cascade_truncated_total", Help: "Total cascades over the fan-out limit handed off to a full reindex"},
+	)
 )
 
 func Register() {
-	prometheus.MustRegister(ProcessedEvents, FailedEvents, DLQEvents)
+	prometheus.MustRegister(ProcessedEvents, FailedEvents, DLQEvents, VersionConflicts, CoalescedEvents, SyncErrors, TransientRetries, DLQRetries, DLQExhausted, OwnedPartitions, WorkerMembers, Rebalances, Leader, CascadeTruncated)
 }
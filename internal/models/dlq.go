@@ -1,6 +1,13 @@
 package models
 
-import "time"
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"time"
+
+	"gorm.io/datatypes"
+)
 
 type DLQ struct {
 	ID         int64     `gorm:"primaryKey;autoIncrement"`
@@ -13,4 +20,69 @@ type DLQ struct {
 	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP"`
 	RetriedAt  *time.Time
 	Resolved   bool `gorm:"default:false"`
+
+	Category          string     `gorm:"index"`     // workers.ErrorCategory of the latest failure
+	Attempts          int        `gorm:"default:0"` // automatic retries made so far
+	NextRetryAt       *time.Time `gorm:"index"`
+	PermanentlyFailed bool       `gorm:"default:false"` // retries exhausted; only a manual retry picks it up
+	Fingerprint       string     `gorm:"index"`         // see ErrorFingerprint
+
+	// context of the latest failure
+	OutboxRow   datatypes.JSON // the outbox row as claimed
+	Document    datatypes.JSON // the document that was sent, if it was built
+	ESStatus    int
+	ESErrorType string
+	ESReason    string
+	ESCausedBy  string
+	WorkerID    string
+}
+
+const (
+	DLQAttemptFailed   = "failed"
+	DLQAttemptResolved = "resolved"
+)
+
+// DLQAttempt is an append-only record of every failure and retry of a DLQ row.
+// Attempt 0 is the failure that created the row.
+type DLQAttempt struct {
+	ID          int64 `gorm:"primaryKey;autoIncrement"`
+	DLQID       int64 `gorm:"index;not null"`
+	Attempt     int
+	Outcome     string
+	Category    string
+	ErrorMsg    string
+	Document    datatypes.JSON
+	ESStatus    int
+	ESResult    string
+	ESErrorType string
+	ESReason    string
+	ESCausedBy  string
+	WorkerID    string
+	CreatedAt   time.Time
+}
+
+// Patterns replaced when normalising an error message, most specific first.
+var errorNormalizers = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ][0-9:.]+(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]*\d[0-9a-f]*[a-f][0-9a-f]*\b|\b[0-9a-f]*[a-f][0-9a-f]*\d[0-9a-f]*\b`), "<hex>"},
+	{regexp.MustCompile(`\d+`), "<n>"},
+}
+
+// NormalizeError strips what varies between occurrences of the same error
+// (uuids, timestamps, hex ids, numbers) so they compare equal.
+func NormalizeError(msg string) string {
+	for _, n := range errorNormalizers {
+		msg = n.re.ReplaceAllString(msg, n.repl)
+	}
+	return msg
+}
+
+// ErrorFingerprint identifies an error by category and normalised message.
+func ErrorFingerprint(category, msg string) string {
+	sum := sha1.Sum([]byte(category + "\x00" + NormalizeError(msg)))
+	return hex.EncodeToString(sum[:8])
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Index migration states, in order. While a migration is dual_write or
+// verifying, sync workers mirror every write for Alias into ToIndex.
+const (
+	MigrationDualWrite = "dual_write"
+	MigrationVerifying = "verifying"
+	MigrationSwapped   = "swapped"
+	MigrationFailed    = "failed"
+)
+
+// IndexMigration records a blue/green rebuild of one alias. It lives in
+// Postgres so every worker process sees when to start dual-writing.
+type IndexMigration struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	Alias     string `gorm:"index;not null"`
+	FromIndex string
+	ToIndex   string `gorm:"not null"`
+	State     string `gorm:"index;not null"`
+	FromCount int64
+	ToCount   int64
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
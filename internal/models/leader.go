@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// LeaderLease is a named lease held by at most one instance at a time; the
+// holder renews it before ExpiresAt or loses it to whoever claims it next.
+type LeaderLease struct {
+	Name       string `gorm:"primaryKey"`
+	Holder     string `gorm:"not null"`
+	AcquiredAt time.Time
+	RenewedAt  time.Time
+	ExpiresAt  time.Time `gorm:"not null"`
+}
@@ -45,12 +45,31 @@ type Project struct {
 }
 
 // ---------------- OUTBOX (for sync events) ----------------
+// Outbox status lifecycle: pending -> in_flight (leased by a worker) -> done | failed.
+// An in_flight row whose lease has expired is treated as pending again.
+const (
+	OutboxPending  = "pending"
+	OutboxInFlight = "in_flight"
+	OutboxDone     = "done"
+	OutboxFailed   = "failed"
+)
+
 type Outbox struct {
-	ID         int64     `gorm:"primaryKey;autoIncrement"`
-	EntityType string    `gorm:"index;not null"`
-	EntityID   uuid.UUID `gorm:"type:uuid;not null"`
-	Op         string    `gorm:"not null"` // UPSERT | DELETE | REINDEX_...
-	Payload    datatypes.JSON
-	CreatedAt  time.Time
-	Processed  bool `gorm:"default:false"`
+	ID             int64     `gorm:"primaryKey;autoIncrement"`
+	EntityType     string    `gorm:"index;index:idx_outboxes_entity,priority:1;not null"`
+	EntityID       uuid.UUID `gorm:"type:uuid;index:idx_outboxes_entity,priority:2;not null"`
+	Op             string    `gorm:"not null"` // UPSERT | DELETE | REINDEX_...
+	Payload        datatypes.JSON
+	CreatedAt      time.Time
+	Status         string `gorm:"index;not null;default:pending"`
+	ClaimedBy      string
+	LeaseExpiresAt *time.Time
+	Attempts       int `gorm:"not null;default:0"`
+
+	// Elasticsearch's answer for this event, recorded when it is acknowledged
+	ESStatus  int
+	ESResult  string
+	ESSeqNo   *int64
+	ESVersion *int64
+	AckedAt   *time.Time
 }
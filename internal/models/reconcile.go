@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ReconcileReport is the persisted outcome of one Postgres-vs-Elasticsearch
+// comparison for one entity type.
+type ReconcileReport struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement"`
+	EntityType   string `gorm:"index;not null"`
+	Index        string
+	Scanned      int64 // rows read from Postgres
+	Documents    int64 // documents scanned in Elasticsearch
+	Missing      int64 // row without a document
+	Orphaned     int64 // document without a row
+	Stale        int64 // document differs from what the builder produces now
+	Repaired     int64 // outbox events enqueued to fix the above
+	Unrepairable int64 // orphans a DELETE would not remove (on_delete=ignore)
+	Samples      datatypes.JSON
+	Error        string
+	StartedAt    time.Time
+	FinishedAt   *time.Time
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReindexCheckpoint records how far a full reindex of one entity type into one
+// index got, so an interrupted run can resume from LastID instead of starting
+// over. TargetIndex is empty for the entity's own index.
+type ReindexCheckpoint struct {
+	EntityType  string    `gorm:"primaryKey"`
+	TargetIndex string    `gorm:"primaryKey;not null;default:''"`
+	LastID      uuid.UUID `gorm:"type:uuid"`
+	Indexed     int64
+	Failed      int64
+	StartedAt   time.Time
+	UpdatedAt   time.Time
+	FinishedAt  *time.Time
+}
+
+// ReindexRequest asks for a full reindex of one entity type, e.g. from a
+// cascade too large to enqueue event by event. It is written in the
+// requesting transaction and marked done once a reindex that started after
+// it was committed has finished.
+type ReindexRequest struct {
+	ID         int64  `gorm:"primaryKey;autoIncrement"`
+	EntityType string `gorm:"index;not null"`
+	Reason     string
+	CreatedAt  time.Time
+	DoneAt     *time.Time `gorm:"index"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// WorkerMember is one live sync worker instance. Instances upsert their row
+// on every heartbeat; rows that stop beating drop out of the partition
+// assignment.
+type WorkerMember struct {
+	ID        string    `gorm:"primaryKey"` // SyncWorker.ID
+	Heartbeat time.Time `gorm:"index;not null"`
+	StartedAt time.Time
+}
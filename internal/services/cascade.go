@@ -0,0 +1,185 @@
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/sirdesai22/sync-service/internal/metrics"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// Dependency is one edge of the cascade graph: documents of Type embed data
+// from the parent entity, and Find returns which of them are affected when
+// the given parent ids change.
+type Dependency struct {
+	Type   string
+	Reason string // shown in logs, e.g. "owner"
+	Find   func(tx *gorm.DB, parentIDs []uuid.UUID) ([]uuid.UUID, error)
+}
+
+const (
+	// MaxCascadeEvents caps how many reindex events one change may enqueue.
+	MaxCascadeEvents = 5000
+	// MaxCascadeDepth caps how many hops a cascade may follow.
+	MaxCascadeDepth = 4
+)
+
+// cascades maps a parent entity type to the entities whose documents embed it.
+var cascades = map[string][]Dependency{
+	"user": {
+		{Type: "project", Reason: "owner", Find: projectsOwnedBy},
+		{Type: "project", Reason: "team member", Find: projectsWithMembers},
+	},
+	"hackathon": {
+		{Type: "project", Reason: "hackathon", Find: projectsInHackathons},
+	},
+}
+
+// RegisterCascade adds an edge to the cascade graph. It is meant to be called
+// during startup, before any writes happen.
+func RegisterCascade(parentType string, dep Dependency) {
+	cascades[parentType] = append(cascades[parentType], dep)
+}
+
+// EnqueueCascade walks the dependency graph from the changed entities and
+// enqueues one UPSERT per affected entity through AddBatchOutboxEvents, in
+// the caller's transaction. Every entity is visited at most once, so cycles
+// terminate. When the fan-out exceeds MaxCascadeEvents or MaxCascadeDepth
+// hops nothing is enqueued for the dependents; instead a ReindexRequest is
+// recorded for every type they may belong to, so the caller's write still
+// commits and the leader reindexes those types afterwards.
+func EnqueueCascade(tx *gorm.DB, entityType string, ids []uuid.UUID) (int, error) {
+	type key struct {
+		Type string
+		ID   uuid.UUID
+	}
+	visited := map[key]bool{}
+	for _, id := range ids {
+		visited[key{entityType, id}] = true
+	}
+
+	pending := map[string][]uuid.UUID{} // type -> ids to enqueue, in discovery order
+	var order []string
+	total := 0
+	frontier := map[string][]uuid.UUID{entityType: ids}
+
+	for depth := 1; len(frontier) > 0; depth++ {
+		next := map[string][]uuid.UUID{}
+		for parentType, parentIDs := range frontier {
+			for _, dep := range cascades[parentType] {
+				if depth > MaxCascadeDepth {
+					return 0, truncateCascade(tx, entityType, fmt.Sprintf("%s -> %s exceeds depth %d", parentType, dep.Type, MaxCascadeDepth))
+				}
+				found, err := dep.Find(tx, parentIDs)
+				if err != nil {
+					return 0, fmt.Errorf("cascade %s -> %s (%s): %w", parentType, dep.Type, dep.Reason, err)
+				}
+				for _, id := range found {
+					k := key{dep.Type, id}
+					if visited[k] {
+						continue
+					}
+					visited[k] = true
+					if total++; total > MaxCascadeEvents {
+						return 0, truncateCascade(tx, entityType, fmt.Sprintf("more than %d events", MaxCascadeEvents))
+					}
+					if _, ok := pending[dep.Type]; !ok {
+						order = append(order, dep.Type)
+					}
+					pending[dep.Type] = append(pending[dep.Type], id)
+					next[dep.Type] = append(next[dep.Type], id)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	if total == 0 {
+		log.Printf("ℹ️ No dependents found for %d %s(s); skipping cascade reindex.", len(ids), entityType)
+		return 0, nil
+	}
+	for _, t := range order {
+		if err := AddBatchOutboxEvents(tx, t, "UPSERT", pending[t]); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// truncateCascade records a ReindexRequest for every entity type reachable
+// from entityType in the cascade graph, in the caller's transaction.
+func truncateCascade(tx *gorm.DB, entityType, reason string) error {
+	types := cascadeTypes(entityType)
+	log.Printf("⚠️ cascade from %s truncated (%s): requesting a full reindex of %v", entityType, reason, types)
+	metrics.CascadeTruncated.Inc()
+	reqs := make([]models.ReindexRequest, len(types))
+	for i, t := range types {
+		reqs[i] = models.ReindexRequest{EntityType: t, Reason: fmt.Sprintf("cascade from %s: %s", entityType, reason)}
+	}
+	return tx.Create(&reqs).Error
+}
+
+// cascadeTypes lists the entity types reachable from entityType.
+func cascadeTypes(entityType string) []string {
+	seen := map[string]bool{}
+	var out []string
+	frontier := []string{entityType}
+	for len(frontier) > 0 {
+		var next []string
+		for _, parent := range frontier {
+			for _, dep := range cascades[parent] {
+				if !seen[dep.Type] {
+					seen[dep.Type] = true
+					out = append(out, dep.Type)
+					next = append(next, dep.Type)
+				}
+			}
+		}
+		frontier = next
+	}
+	return out
+}
+
+// enqueueDelete enqueues a DELETE for each id plus the cascade for their
+// dependents. It must run before the rows are deleted so the cascade can
+// still find dependents through them. Dependents deleted in the same
+// transaction get their own (later, so winning) DELETE from another call.
+func enqueueDelete(tx *gorm.DB, entityType string, ids []uuid.UUID) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	n, err := EnqueueCascade(tx, entityType, ids)
+	if err != nil {
+		return 0, err
+	}
+	return n, AddBatchOutboxEvents(tx, entityType, "DELETE", ids)
+}
+
+func projectsOwnedBy(tx *gorm.DB, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := tx.Model(&models.Project{}).Where("owner_id IN ?", userIDs).Pluck("id", &ids).Error
+	return ids, err
+}
+
+func projectsInHackathons(tx *gorm.DB, hackathonIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := tx.Model(&models.Project{}).Where("hackathon_id IN ?", hackathonIDs).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// projectsWithMembers finds projects listing any of the users in their
+// team_members JSON array.
+func projectsWithMembers(tx *gorm.DB, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	members := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		members[i] = id.String()
+	}
+	var ids []uuid.UUID
+	err := tx.Model(&models.Project{}).
+		Where("jsonb_typeof(team_members) = 'array'").
+		Where("EXISTS (SELECT 1 FROM jsonb_array_elements_text(team_members) AS m(id) WHERE m.id IN ?)", members).
+		Pluck("id", &ids).Error
+	return ids, err
+}
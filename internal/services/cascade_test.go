@@ -0,0 +1,38 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCascadeTypes(t *testing.T) {
+	tests := []struct {
+		entityType string
+		want       []string
+	}{
+		{"user", []string{"project"}},
+		{"hackathon", []string{"project"}},
+		{"project", nil},
+		{"unknown", nil},
+	}
+	for _, tt := range tests {
+		if got := cascadeTypes(tt.entityType); !slices.Equal(got, tt.want) {
+			t.Errorf("cascadeTypes(%q) = %v, want %v", tt.entityType, got, tt.want)
+		}
+	}
+}
+
+// Cycles and diamonds in the graph list each type once.
+func TestCascadeTypesCycle(t *testing.T) {
+	saved := cascades
+	t.Cleanup(func() { cascades = saved })
+	cascades = map[string][]Dependency{
+		"a": {{Type: "b"}, {Type: "c"}},
+		"b": {{Type: "c"}, {Type: "a"}},
+		"c": {{Type: "d"}},
+	}
+	want := []string{"b", "c", "a", "d"}
+	if got := cascadeTypes("a"); !slices.Equal(got, want) {
+		t.Errorf("cascadeTypes(a) = %v, want %v", got, want)
+	}
+}
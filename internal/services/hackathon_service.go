@@ -0,0 +1,68 @@
+package services
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// UpdateHackathon updates a hackathon and creates outbox entries for the
+// hackathon itself and every project document embedding it.
+func UpdateHackathon(db *gorm.DB, id uuid.UUID, updates map[string]any) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Hackathon{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		var hackathon models.Hackathon
+		if err := tx.First(&hackathon, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := AddOutboxEvent(tx, "hackathon", hackathon.ID, "UPSERT", hackathon); err != nil {
+			return err
+		}
+
+		n, err := EnqueueCascade(tx, "hackathon", []uuid.UUID{hackathon.ID})
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("🔁 Cascade reindex triggered for %d documents of hackathon %s", n, hackathon.Name)
+		}
+		return nil
+	})
+}
+
+// DeleteHackathon deletes a hackathon and, since projects reference their
+// hackathon, its projects, enqueuing a DELETE for each of them.
+func DeleteHackathon(db *gorm.DB, id uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		projects, err := projectsInHackathons(tx, []uuid.UUID{id})
+		if err != nil {
+			return err
+		}
+		if _, err := enqueueDelete(tx, "hackathon", []uuid.UUID{id}); err != nil {
+			return err
+		}
+		if _, err := enqueueDelete(tx, "project", projects); err != nil {
+			return err
+		}
+
+		if len(projects) > 0 {
+			if err := tx.Delete(&models.Project{}, "id IN ?", projects).Error; err != nil {
+				return err
+			}
+		}
+		res := tx.Delete(&models.Hackathon{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		log.Printf("🗑️ Deleted hackathon %s with %d projects", id, len(projects))
+		return nil
+	})
+}
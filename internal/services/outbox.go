@@ -10,7 +10,12 @@ import (
 	"gorm.io/gorm"
 )
 
-// AddOutboxEvent inserts one event into the outbox (same as before)
+// OutboxChannel is the Postgres NOTIFY channel the sync worker listens on.
+// Notifications are sent inside the writing transaction, so they are only
+// delivered once the outbox rows are committed.
+const OutboxChannel = "outbox_events"
+
+// AddOutboxEvent inserts one event into the outbox and wakes the sync worker
 func AddOutboxEvent(tx *gorm.DB, entityType string, entityID uuid.UUID, op string, payload any) error {
 	data, _ := json.Marshal(payload)
 
@@ -25,23 +30,37 @@ func AddOutboxEvent(tx *gorm.DB, entityType string, entityID uuid.UUID, op strin
 		log.Printf("❌ Failed to create outbox event: %v", err)
 		return err
 	}
-	return nil
+	return notifyOutbox(tx, entityType)
 }
 
 // AddBatchOutboxEvents inserts multiple events efficiently.
 // Used for cascading updates (e.g., reindex all projects for a user).
 func AddBatchOutboxEvents(tx *gorm.DB, entityType string, op string, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	events := make([]models.Outbox, 0, len(ids))
 	for _, id := range ids {
-		event := models.Outbox{
+		events = append(events, models.Outbox{
 			EntityType: entityType,
 			EntityID:   id,
 			Op:         op,
-		}
-		if err := tx.Create(&event).Error; err != nil {
-			log.Printf("❌ Failed to insert batch outbox for %s: %v", entityType, err)
-			return err
-		}
+		})
+	}
+	if err := tx.CreateInBatches(&events, 500).Error; err != nil {
+		log.Printf("❌ Failed to insert batch outbox for %s: %v", entityType, err)
+		return err
 	}
 	log.Printf("📦 %d outbox events created for %s", len(ids), entityType)
+	return notifyOutbox(tx, entityType)
+}
+
+// notifyOutbox fires pg_notify on OutboxChannel. Postgres collapses identical
+// notifications within a transaction, so calling this per row is cheap.
+func notifyOutbox(tx *gorm.DB, entityType string) error {
+	if err := tx.Exec("SELECT pg_notify(?, ?)", OutboxChannel, entityType).Error; err != nil {
+		log.Printf("❌ Failed to notify outbox listeners: %v", err)
+		return err
+	}
 	return nil
 }
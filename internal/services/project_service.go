@@ -0,0 +1,31 @@
+package services
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// DeleteProject deletes a project and enqueues its DELETE, plus reindex
+// events for anything registered as embedding it.
+func DeleteProject(db *gorm.DB, id uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		n, err := enqueueDelete(tx, "project", []uuid.UUID{id})
+		if err != nil {
+			return err
+		}
+		res := tx.Delete(&models.Project{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if n > 0 {
+			log.Printf("🔁 Cascade reindex triggered for %d documents of project %s", n, id)
+		}
+		return nil
+	})
+}
@@ -10,7 +10,7 @@ import (
 
 // UpdateUser updates a user and creates outbox entries for:
 // 1️⃣ The user itself (UPSERT)
-// 2️⃣ All related projects (so they get reindexed)
+// 2️⃣ Every document embedding the user (owned and team-member projects)
 func UpdateUser(db *gorm.DB, id uuid.UUID, updates map[string]any) error {
 	return db.Transaction(func(tx *gorm.DB) error {
 		// --- Step 1: Update the user record ---
@@ -27,26 +27,59 @@ func UpdateUser(db *gorm.DB, id uuid.UUID, updates map[string]any) error {
 			return err
 		}
 
-		// --- Step 3: Find all related projects ---
-		var projects []models.Project
-		if err := tx.Where("owner_id = ?", id).Find(&projects).Error; err != nil {
+		// --- Step 3: Enqueue reindex events for dependents ---
+		n, err := EnqueueCascade(tx, "user", []uuid.UUID{user.ID})
+		if err != nil {
 			return err
 		}
-		if len(projects) == 0 {
-			log.Println("ℹ️ No projects found for this user; skipping cascade reindex.")
-			return nil
+		if n > 0 {
+			log.Printf("🔁 Cascade reindex triggered for %d documents of user %s", n, user.Username)
 		}
+		return nil
+	})
+}
 
-		// --- Step 4: Enqueue reindex events for each project ---
-		projectIDs := make([]uuid.UUID, 0, len(projects))
-		for _, p := range projects {
-			projectIDs = append(projectIDs, p.ID)
+// DeleteUser deletes a user and, since projects reference their owner, the
+// projects they own. It creates outbox entries for:
+// 1️⃣ The user and every owned project (DELETE)
+// 2️⃣ Every other project the user was a team member of (UPSERT), after
+// removing them from its team_members
+func DeleteUser(db *gorm.DB, id uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		owned, err := projectsOwnedBy(tx, []uuid.UUID{id})
+		if err != nil {
+			return err
 		}
-		if err := AddBatchOutboxEvents(tx, "project", "UPSERT", projectIDs); err != nil {
+
+		// --- Step 1: Enqueue events while dependents can still be found ---
+		n, err := enqueueDelete(tx, "user", []uuid.UUID{id})
+		if err != nil {
+			return err
+		}
+		if _, err := enqueueDelete(tx, "project", owned); err != nil {
 			return err
 		}
 
-		log.Printf("🔁 Cascade reindex triggered for %d projects of user %s", len(projectIDs), user.Username)
+		// --- Step 2: Delete the rows ---
+		if err := tx.Exec(`
+			UPDATE projects SET team_members = team_members - ?::text
+			WHERE jsonb_typeof(team_members) = 'array' AND team_members @> jsonb_build_array(?::text)`,
+			id.String(), id.String()).Error; err != nil {
+			return err
+		}
+		if len(owned) > 0 {
+			if err := tx.Delete(&models.Project{}, "id IN ?", owned).Error; err != nil {
+				return err
+			}
+		}
+		res := tx.Delete(&models.User{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		log.Printf("🗑️ Deleted user %s with %d owned projects (%d documents to update)", id, len(owned), n)
 		return nil
 	})
 }
@@ -0,0 +1,220 @@
+// internal/syncconfig/config.go
+// this file loads and validates the declarative table -> index sync configuration
+package syncconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/workers"
+	"gorm.io/gorm"
+)
+
+// Config declares extra synced entities that need no Go code.
+type Config struct {
+	Entities []Entity `json:"entities"`
+}
+
+// Entity maps one Postgres table onto one Elasticsearch index. The primary
+// key must be a uuid column, since outbox entity ids are uuids.
+type Entity struct {
+	Type       string          `json:"type"`        // outbox entity_type
+	Table      string          `json:"table"`       // source table
+	PrimaryKey string          `json:"primary_key"` // defaults to "id"
+	Fields     []Field         `json:"fields"`
+	Index      string          `json:"index"`         // alias, backed by <index>_v<index_version>
+	IndexVer   int             `json:"index_version"` // defaults to 1; bump with the mapping
+	Mapping    json.RawMessage `json:"mapping"`       // create-index body; optional
+	OnDelete   string          `json:"on_delete"`     // "delete" (default) or "ignore"
+}
+
+// Field projects one column into one document field.
+type Field struct {
+	Column    string `json:"column"`
+	Field     string `json:"field"`      // defaults to the column name
+	JSONArray bool   `json:"json_array"` // unpack a JSON array column (e.g. skills, tracks)
+}
+
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Load reads a JSON config file and validates it without touching any
+// external system; see Apply for the checks against Postgres.
+func Load(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.DisallowUnknownFields()
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i := range cfg.Entities {
+		cfg.Entities[i].setDefaults()
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (e *Entity) setDefaults() {
+	if e.PrimaryKey == "" {
+		e.PrimaryKey = "id"
+	}
+	if e.OnDelete == "" {
+		e.OnDelete = "delete"
+	}
+	if e.IndexVer == 0 {
+		e.IndexVer = 1
+	}
+	for i := range e.Fields {
+		if e.Fields[i].Field == "" {
+			e.Fields[i].Field = e.Fields[i].Column
+		}
+	}
+}
+
+// Validate checks the config for internal consistency.
+func (c *Config) Validate() error {
+	var errs []error
+	seen := map[string]bool{}
+	for i, e := range c.Entities {
+		where := fmt.Sprintf("entities[%d] (%s)", i, e.Type)
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf(where+": "+format, args...))
+		}
+
+		switch {
+		case e.Type == "":
+			fail("type is required")
+		case seen[e.Type]:
+			fail("duplicate type")
+		}
+		seen[e.Type] = true
+		if _, err := workers.HandlerFor(e.Type); err == nil {
+			fail("type is already handled by a built-in handler")
+		}
+		if !identRe.MatchString(e.Table) {
+			fail("invalid table %q", e.Table)
+		}
+		if !identRe.MatchString(e.PrimaryKey) {
+			fail("invalid primary_key %q", e.PrimaryKey)
+		}
+		if e.Index == "" || e.Index != strings.ToLower(e.Index) {
+			fail("index must be a non-empty lowercase name, got %q", e.Index)
+		}
+		if elastic.IsManagedAlias(e.Index) {
+			fail("index %q is already declared", e.Index)
+		}
+		if e.IndexVer < 1 {
+			fail("index_version must be >= 1")
+		}
+		if e.OnDelete != "delete" && e.OnDelete != "ignore" {
+			fail("on_delete must be \"delete\" or \"ignore\", got %q", e.OnDelete)
+		}
+		if len(e.Fields) == 0 {
+			fail("at least one field is required")
+		}
+		fields := map[string]bool{}
+		for _, f := range e.Fields {
+			if !identRe.MatchString(f.Column) {
+				fail("invalid column %q", f.Column)
+			}
+			if fields[f.Field] {
+				fail("field %q is projected twice", f.Field)
+			}
+			fields[f.Field] = true
+		}
+
+		if len(e.Mapping) > 0 {
+			var body struct {
+				Mappings struct {
+					Properties map[string]json.RawMessage `json:"properties"`
+				} `json:"mappings"`
+			}
+			if err := json.Unmarshal(e.Mapping, &body); err != nil {
+				fail("mapping is not a valid index body: %v", err)
+				continue
+			}
+			if props := body.Mappings.Properties; props != nil {
+				for f := range fields {
+					if _, ok := props[f]; !ok {
+						fail("field %q is missing from mapping.mappings.properties", f)
+					}
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Apply verifies every entity against the live schema, declares and creates
+// the aliased indices and registers a handler per entity with the sync
+// worker. It must run before the worker starts.
+func (c *Config) Apply(ctx context.Context, db *gorm.DB, client *es.Client) error {
+	for _, e := range c.Entities {
+		if err := e.checkSchema(ctx, db); err != nil {
+			return fmt.Errorf("entity %s: %w", e.Type, err)
+		}
+	}
+	for _, e := range c.Entities {
+		spec := elastic.IndexSpec{Alias: e.Index, Version: e.IndexVer, Body: string(e.Mapping)}
+		if spec.Body == "" {
+			spec.Body = "{}"
+		}
+		if err := elastic.RegisterIndex(spec); err != nil {
+			return fmt.Errorf("entity %s: %w", e.Type, err)
+		}
+		if err := elastic.EnsureAlias(ctx, client, spec); err != nil {
+			return fmt.Errorf("entity %s: %w", e.Type, err)
+		}
+		workers.RegisterEntity(e.Type, &tableHandler{entity: e})
+	}
+	return nil
+}
+
+// checkSchema makes sure the table and every referenced column exist, so a
+// typo fails at startup rather than as a DLQ row per event.
+func (e Entity) checkSchema(ctx context.Context, db *gorm.DB) error {
+	var cols []struct {
+		ColumnName string
+		DataType   string
+	}
+	err := db.WithContext(ctx).Raw(`
+		SELECT column_name, data_type FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ?`, e.Table).Scan(&cols).Error
+	if err != nil {
+		return err
+	}
+	if len(cols) == 0 {
+		return fmt.Errorf("table %q does not exist", e.Table)
+	}
+	types := make(map[string]string, len(cols))
+	for _, c := range cols {
+		types[c.ColumnName] = c.DataType
+	}
+	if t, ok := types[e.PrimaryKey]; !ok {
+		return fmt.Errorf("primary key column %q does not exist in %s", e.PrimaryKey, e.Table)
+	} else if t != "uuid" {
+		return fmt.Errorf("primary key column %q must be uuid, is %s", e.PrimaryKey, t)
+	}
+	var missing []string
+	for _, f := range e.Fields {
+		if _, ok := types[f.Column]; !ok {
+			missing = append(missing, f.Column)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("columns %s do not exist in %s", strings.Join(missing, ", "), e.Table)
+	}
+	return nil
+}
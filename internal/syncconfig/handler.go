@@ -0,0 +1,105 @@
+// internal/syncconfig/handler.go
+// this file implements workers.EntityHandler for config-declared tables
+package syncconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// pkAlias carries the primary key as text so it parses the same way
+// regardless of how the driver returns uuid columns.
+const pkAlias = "__sync_pk"
+
+type tableHandler struct {
+	entity Entity
+}
+
+func (h *tableHandler) Index() string { return h.entity.Index }
+
+func (h *tableHandler) DeleteAction() string {
+	if h.entity.OnDelete == "ignore" {
+		return ""
+	}
+	return "delete"
+}
+
+func (h *tableHandler) Load(ctx context.Context, db *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]any, error) {
+	e := h.entity
+	cols := make([]string, 0, len(e.Fields)+1)
+	cols = append(cols, fmt.Sprintf("%q::text AS %s", e.PrimaryKey, pkAlias))
+	for _, f := range e.Fields {
+		cols = append(cols, fmt.Sprintf("%q", f.Column))
+	}
+
+	// identifiers were validated against identRe when the config was loaded
+	query := fmt.Sprintf("SELECT %s FROM %q WHERE %q IN ?", strings.Join(cols, ", "), e.Table, e.PrimaryKey)
+	var rows []map[string]any
+	if err := db.WithContext(ctx).Raw(query, ids).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	out := make(map[uuid.UUID]any, len(rows))
+	for _, r := range rows {
+		id, err := uuid.Parse(fmt.Sprint(r[pkAlias]))
+		if err != nil {
+			return nil, fmt.Errorf("%s: primary key %v: %w", e.Table, r[pkAlias], err)
+		}
+		out[id] = r
+	}
+	return out, nil
+}
+
+func (h *tableHandler) ListIDs(ctx context.Context, db *gorm.DB, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	e := h.entity
+	query := fmt.Sprintf("SELECT %q FROM %q WHERE %q > ? ORDER BY %q ASC LIMIT ?", e.PrimaryKey, e.Table, e.PrimaryKey, e.PrimaryKey)
+	var ids []uuid.UUID
+	err := db.WithContext(ctx).Raw(query, after, limit).Scan(&ids).Error
+	return ids, err
+}
+
+func (h *tableHandler) Build(row any) ([]byte, error) {
+	r, ok := row.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected row type %T", h.entity.Table, row)
+	}
+	doc := make(map[string]any, len(h.entity.Fields))
+	for _, f := range h.entity.Fields {
+		v := r[f.Column]
+		if f.JSONArray {
+			arr, err := unpackArray(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", h.entity.Table, f.Column, err)
+			}
+			v = arr
+		}
+		doc[f.Field] = v
+	}
+	return json.Marshal(doc)
+}
+
+// unpackArray decodes a json/jsonb column holding an array, the way
+// BuildUserDoc unpacks Skills.
+func unpackArray(v any) ([]any, error) {
+	var raw []byte
+	switch t := v.(type) {
+	case nil:
+		return []any{}, nil
+	case []byte:
+		raw = t
+	case string:
+		raw = []byte(t)
+	default:
+		return nil, fmt.Errorf("expected a JSON array column, got %T", v)
+	}
+	var arr []any
+	if err := json.Unmarshal(raw, &arr); err != nil {
+		return nil, err
+	}
+	return arr, nil
+}
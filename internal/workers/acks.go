@@ -0,0 +1,239 @@
+// internal/workers/acks.go
+// this file correlates bulk indexer callbacks back to the outbox rows that produced them
+package workers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/metrics"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// ackTracker follows one claimed batch through the bulk indexer. Every item it
+// builds carries its outbox row, and the row is only finalised (done or
+// failed, with the Elasticsearch result recorded) from the bulk callbacks,
+// i.e. after Elasticsearch has actually answered for it.
+type ackTracker struct {
+	db       *gorm.DB
+	workerID string
+	started  time.Time
+
+	// onOutcome, when set, is told whether each op ended up acknowledged,
+	// with the response item and, on failure, the error message.
+	onOutcome func(op *entityOp, ok bool, res esutil.BulkIndexerResponseItem, msg string)
+
+	mu      sync.Mutex
+	added   int
+	settled int
+	ok      int
+	failed  int
+	sealed  bool
+}
+
+func newAckTracker(db *gorm.DB, workerID string) *ackTracker {
+	return &ackTracker{db: db, workerID: workerID, started: time.Now()}
+}
+
+// item builds a bulk item for op whose callbacks acknowledge every outbox row
+// folded into it.
+func (t *ackTracker) item(op *entityOp, index, action string, body []byte) esutil.BulkIndexerItem {
+	t.mu.Lock()
+	t.added++
+	t.mu.Unlock()
+
+	// Outbox ids are assigned in commit order per entity, so using them as
+	// external versions makes Elasticsearch reject any write that is older
+	// than what the document already reflects, whichever worker sends it.
+	version := op.Version
+	return esutil.BulkIndexerItem{
+		Action:      action,
+		Index:       index,
+		DocumentID:  op.Event.EntityID.String(),
+		Body:        bytes.NewReader(body),
+		Version:     &version,
+		VersionType: "external",
+		// never let a missing alias auto-create a dynamically mapped index
+		RequireAlias: elastic.IsManagedAlias(index),
+		OnSuccess: func(_ context.Context, _ esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem) {
+			t.succeeded(op, res)
+		},
+		OnFailure: func(_ context.Context, _ esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
+			if err == nil && isVersionConflict(res) {
+				t.superseded(op, res)
+				return
+			}
+			if err == nil && isAlreadyDeleted(action, res) {
+				t.succeeded(op, res)
+				return
+			}
+			t.failedWith(op, body, res, err)
+		},
+	}
+}
+
+// dropped releases an item that was built but never accepted by bi.Add.
+func (t *ackTracker) dropped() {
+	t.mu.Lock()
+	t.added--
+	t.mu.Unlock()
+	t.maybeReport()
+}
+
+// seal marks the batch as fully handed to the bulk indexer; the summary is
+// logged once every item added so far has been acknowledged.
+func (t *ackTracker) seal() {
+	t.mu.Lock()
+	t.sealed = true
+	t.mu.Unlock()
+	t.maybeReport()
+}
+
+func (t *ackTracker) succeeded(op *entityOp, res esutil.BulkIndexerResponseItem) {
+	if err := AckOutbox(t.db, op.ids(), models.OutboxDone, res); err != nil {
+		log.Printf("❌ failed to ack outbox_id=%d: %v", op.Event.ID, err)
+	}
+	t.resolveRetry(op, res)
+	t.outcome(op, true, res, "")
+	metrics.ProcessedEvents.Add(float64(len(op.Merged)))
+	log.Printf("✅ synced %s id=%s (outbox=%d result=%s version=%d)", res.Index, res.DocumentID, op.Event.ID, res.Result, res.Version)
+	t.settle(true)
+}
+
+// superseded acknowledges an event Elasticsearch refused because the document
+// already carries a newer version. That is the expected outcome when events
+// for one entity race across workers, so it is not a failure.
+func (t *ackTracker) superseded(op *entityOp, res esutil.BulkIndexerResponseItem) {
+	res.Result = "version_conflict"
+	if err := AckOutbox(t.db, op.ids(), models.OutboxDone, res); err != nil {
+		log.Printf("❌ failed to ack outbox_id=%d: %v", op.Event.ID, err)
+	}
+	t.resolveRetry(op, res)
+	t.outcome(op, true, res, "")
+	metrics.VersionConflicts.Inc()
+	log.Printf("⏭️ skipped %s/%s outbox=%d: document already newer", op.Event.EntityType, op.Event.EntityID, op.Event.ID)
+	t.settle(true)
+}
+
+// skipped acknowledges an op its handler chose not to send to Elasticsearch.
+func (t *ackTracker) skipped(op *entityOp) {
+	res := esutil.BulkIndexerResponseItem{Result: "skipped"}
+	if err := AckOutbox(t.db, op.ids(), models.OutboxDone, res); err != nil {
+		log.Printf("❌ failed to ack outbox_id=%d: %v", op.Event.ID, err)
+	}
+	t.resolveRetry(op, res)
+	t.outcome(op, true, res, "")
+	metrics.ProcessedEvents.Add(float64(len(op.Merged)))
+}
+
+func (t *ackTracker) failedWith(op *entityOp, body []byte, res esutil.BulkIndexerResponseItem, err error) {
+	msg := bulkErrorMessage(res, err)
+	cat := classifyBulk(res, err)
+	if deferTransient(t.db, op, cat, msg) {
+		t.outcome(op, false, res, msg)
+		t.settle(false)
+		return
+	}
+	metrics.FailedEvents.Inc()
+	f := DLQFailure{Event: op.Event, Retry: op.Retry, Category: cat, Message: msg, Document: body, WorkerID: t.workerID}
+	if err == nil {
+		f.Response = &res
+	}
+	WriteDLQ(t.db, f)
+	if err := AckOutbox(t.db, op.ids(), models.OutboxFailed, res); err != nil {
+		log.Printf("❌ failed to ack outbox_id=%d: %v", op.Event.ID, err)
+	}
+	log.Printf("💀 bulk failure outbox=%d %s/%s [%s]: %s", op.Event.ID, op.Event.EntityType, op.Event.EntityID, cat, msg)
+	t.outcome(op, false, res, msg)
+	t.settle(false)
+}
+
+// deferTransient counts a failure and, when it is transient and the op has
+// claims left, hands its outbox rows back with a short backoff so the DLQ
+// only sees failures that outlast a few attempts. DLQ retries have their own
+// schedule and are never deferred.
+func deferTransient(db *gorm.DB, op *entityOp, cat ErrorCategory, msg string) bool {
+	metrics.SyncErrors.WithLabelValues(string(cat)).Inc()
+	if cat != CategoryTransient || op.Retry != nil || op.attempts() >= inProcessRetry.MaxAttempts {
+		return false
+	}
+	wait := inProcessRetry.Backoff(op.attempts())
+	if err := DeferOutbox(db, op.ids(), wait); err != nil {
+		log.Printf("❌ failed to defer outbox_id=%d: %v", op.Event.ID, err)
+		return false
+	}
+	metrics.TransientRetries.Inc()
+	log.Printf("⏳ transient failure outbox=%d %s/%s (attempt %d), retrying in %s: %s",
+		op.Event.ID, op.Event.EntityType, op.Event.EntityID, op.attempts(), wait.Round(time.Millisecond), msg)
+	return true
+}
+
+// resolveRetry closes the DLQ row a retried op came from.
+func (t *ackTracker) resolveRetry(op *entityOp, res esutil.BulkIndexerResponseItem) {
+	if op.Retry == nil {
+		return
+	}
+	if err := ResolveDLQ(t.db, *op.Retry, t.workerID, res); err != nil {
+		dlqLogger.Printf("failed to resolve id=%d: %v", op.Retry.ID, err)
+		return
+	}
+	dlqLogger.Printf("✅ id=%d resolved after %d attempts", op.Retry.ID, op.Retry.Attempts+1)
+}
+
+func (t *ackTracker) outcome(op *entityOp, ok bool, res esutil.BulkIndexerResponseItem, msg string) {
+	if t.onOutcome != nil {
+		t.onOutcome(op, ok, res, msg)
+	}
+}
+
+func (t *ackTracker) settle(ok bool) {
+	t.mu.Lock()
+	t.settled++
+	if ok {
+		t.ok++
+	} else {
+		t.failed++
+	}
+	t.mu.Unlock()
+	t.maybeReport()
+}
+
+func (t *ackTracker) maybeReport() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.sealed || t.settled != t.added || t.added == 0 {
+		return
+	}
+	log.Printf("bulk batch acknowledged ok=%d failed=%d in %s", t.ok, t.failed, time.Since(t.started).Round(time.Millisecond))
+	t.sealed = false // report once
+}
+
+func isVersionConflict(res esutil.BulkIndexerResponseItem) bool {
+	return res.Status == http.StatusConflict && res.Error.Type == "version_conflict_engine_exception"
+}
+
+// isAlreadyDeleted reports a delete of a document that does not exist, which
+// is the state the delete asked for.
+func isAlreadyDeleted(action string, res esutil.BulkIndexerResponseItem) bool {
+	return action == "delete" && res.Status == http.StatusNotFound && res.Result == "not_found"
+}
+
+// bulkErrorMessage renders whichever error detail the bulk indexer gave us.
+func bulkErrorMessage(res esutil.BulkIndexerResponseItem, err error) string {
+	switch {
+	case err != nil:
+		return err.Error()
+	case res.Error.Reason != "":
+		return fmt.Sprintf("%s: %s", res.Error.Type, res.Error.Reason)
+	default:
+		return fmt.Sprintf("status=%d", res.Status)
+	}
+}
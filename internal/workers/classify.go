@@ -0,0 +1,82 @@
+// internal/workers/classify.go
+// this file sorts sync failures into categories that decide how (and whether) they are retried
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"gorm.io/gorm"
+)
+
+type ErrorCategory string
+
+const (
+	CategoryTransient     ErrorCategory = "transient"     // overload or connectivity; retrying will likely work
+	CategoryNotFound      ErrorCategory = "not_found"     // the row or index is gone
+	CategoryMapping       ErrorCategory = "mapping"       // the document does not fit the index mapping
+	CategorySerialization ErrorCategory = "serialization" // the document could not be built
+	CategoryUnknown       ErrorCategory = "unknown"
+)
+
+var ErrorCategories = []ErrorCategory{CategoryTransient, CategoryNotFound, CategoryMapping, CategorySerialization, CategoryUnknown}
+
+// classifyError categorises a failure that happened before Elasticsearch
+// answered: loading rows, building documents or talking to the cluster.
+func classifyError(err error) ErrorCategory {
+	var (
+		netErr    net.Error
+		syntax    *json.SyntaxError
+		unmarshal *json.UnmarshalTypeError
+		marshal   *json.MarshalerError
+		unsupType *json.UnsupportedTypeError
+		unsupVal  *json.UnsupportedValueError
+	)
+	switch {
+	case err == nil:
+		return CategoryUnknown
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return CategoryNotFound
+	case errors.As(err, &syntax), errors.As(err, &unmarshal), errors.As(err, &marshal),
+		errors.As(err, &unsupType), errors.As(err, &unsupVal):
+		return CategorySerialization
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF),
+		errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.As(err, &netErr):
+		return CategoryTransient
+	}
+	return CategoryUnknown
+}
+
+// classifyBulk categorises a bulk item failure from its response (or the
+// transport error when the whole flush failed).
+func classifyBulk(res esutil.BulkIndexerResponseItem, err error) ErrorCategory {
+	if err != nil {
+		return classifyError(err)
+	}
+	switch res.Error.Type {
+	case "es_rejected_execution_exception", "circuit_breaking_exception",
+		"unavailable_shards_exception", "no_shard_available_action_exception",
+		"cluster_block_exception", "process_cluster_event_timeout_exception":
+		return CategoryTransient
+	case "strict_dynamic_mapping_exception", "mapper_parsing_exception",
+		"document_parsing_exception", "illegal_argument_exception":
+		return CategoryMapping
+	case "index_not_found_exception", "document_missing_exception":
+		return CategoryNotFound
+	case "json_parse_exception", "x_content_parse_exception", "not_x_content_exception":
+		return CategorySerialization
+	}
+	switch res.Status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CategoryTransient
+	case http.StatusNotFound:
+		return CategoryNotFound
+	}
+	return CategoryUnknown
+}
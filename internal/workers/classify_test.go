@@ -0,0 +1,85 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"gorm.io/gorm"
+)
+
+func TestClassifyError(t *testing.T) {
+	var syntax *json.SyntaxError
+	syntaxErr := json.Unmarshal([]byte("{"), &struct{}{})
+	if !errors.As(syntaxErr, &syntax) {
+		t.Fatalf("expected a json.SyntaxError, got %T", syntaxErr)
+	}
+	_, marshalErr := json.Marshal(map[string]any{"c": make(chan int)})
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCategory
+	}{
+		{"nil", nil, CategoryUnknown},
+		{"record not found", gorm.ErrRecordNotFound, CategoryNotFound},
+		{"wrapped record not found", fmt.Errorf("load: %w", gorm.ErrRecordNotFound), CategoryNotFound},
+		{"json syntax", syntaxErr, CategorySerialization},
+		{"json unsupported type", marshalErr, CategorySerialization},
+		{"deadline", context.DeadlineExceeded, CategoryTransient},
+		{"unexpected eof", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), CategoryTransient},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, CategoryTransient},
+		{"connection reset", fmt.Errorf("flush: %w", syscall.ECONNRESET), CategoryTransient},
+		{"canceled", context.Canceled, CategoryUnknown},
+		{"other", errors.New("boom"), CategoryUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %s, want %s", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyBulk(t *testing.T) {
+	item := func(status int, errType string) esutil.BulkIndexerResponseItem {
+		var res esutil.BulkIndexerResponseItem
+		res.Status = status
+		res.Error.Type = errType
+		return res
+	}
+	tests := []struct {
+		name string
+		res  esutil.BulkIndexerResponseItem
+		err  error
+		want ErrorCategory
+	}{
+		{"transport error", item(0, ""), syscall.ECONNREFUSED, CategoryTransient},
+		{"rejected execution", item(http.StatusTooManyRequests, "es_rejected_execution_exception"), nil, CategoryTransient},
+		{"circuit breaker", item(http.StatusTooManyRequests, "circuit_breaking_exception"), nil, CategoryTransient},
+		{"strict mapping", item(http.StatusBadRequest, "strict_dynamic_mapping_exception"), nil, CategoryMapping},
+		{"mapper parsing", item(http.StatusBadRequest, "mapper_parsing_exception"), nil, CategoryMapping},
+		{"index not found", item(http.StatusNotFound, "index_not_found_exception"), nil, CategoryNotFound},
+		{"json parse", item(http.StatusBadRequest, "json_parse_exception"), nil, CategorySerialization},
+		{"bare 429", item(http.StatusTooManyRequests, ""), nil, CategoryTransient},
+		{"bare 503", item(http.StatusServiceUnavailable, ""), nil, CategoryTransient},
+		{"bare 404", item(http.StatusNotFound, ""), nil, CategoryNotFound},
+		{"type wins over status", item(http.StatusServiceUnavailable, "mapper_parsing_exception"), nil, CategoryMapping},
+		{"unknown", item(http.StatusBadRequest, "some_other_exception"), nil, CategoryUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyBulk(tt.res, tt.err); got != tt.want {
+				t.Errorf("classifyBulk() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// internal/workers/coalesce.go
+// this file folds all events of a claimed batch that target the same entity into one operation
+package workers
+
+import (
+	"github.com/google/uuid"
+	"github.com/sirdesai22/sync-service/internal/models"
+)
+
+// entityOp is the single effective operation for one entity within a batch.
+type entityOp struct {
+	Event   models.Outbox   // the event that is applied (latest UPSERT, or a DELETE)
+	Merged  []models.Outbox // every event folded into this op, Event included
+	Version int64           // highest outbox id seen, used as the external version
+	Retry   *models.DLQ     // set when the op re-applies a dead-lettered event
+}
+
+func (op *entityOp) ids() []int64 {
+	ids := make([]int64, 0, len(op.Merged))
+	for _, e := range op.Merged {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+// attempts is how often the most-claimed event of the op has been claimed.
+func (op *entityOp) attempts() int {
+	n := 0
+	for _, e := range op.Merged {
+		n = max(n, e.Attempts)
+	}
+	return n
+}
+
+func (op *entityOp) isDelete() bool { return op.Event.Op == "DELETE" }
+
+type entityKey struct {
+	Type string
+	ID   uuid.UUID
+}
+
+// coalesce collapses events (already ordered by id) per entity_type/entity_id.
+// The last write wins, except that a DELETE dominates any UPSERT in the same
+// batch since ids are never reused. Ops keep the order of their first event,
+// and the number of events folded away is returned alongside.
+func coalesce(events []models.Outbox) ([]*entityOp, int) {
+	byKey := make(map[entityKey]*entityOp, len(events))
+	ops := make([]*entityOp, 0, len(events))
+	for _, e := range events {
+		key := entityKey{e.EntityType, e.EntityID}
+		op, ok := byKey[key]
+		if !ok {
+			op = &entityOp{Event: e}
+			byKey[key] = op
+			ops = append(ops, op)
+		} else if !op.isDelete() || e.Op == "DELETE" {
+			op.Event = e
+		}
+		op.Merged = append(op.Merged, e)
+		op.Version = max(op.Version, e.ID)
+	}
+	return ops, len(events) - len(ops)
+}
@@ -0,0 +1,92 @@
+package workers
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirdesai22/sync-service/internal/models"
+)
+
+func TestCoalesce(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	ev := func(id int64, typ string, entity uuid.UUID, op string) models.Outbox {
+		return models.Outbox{ID: id, EntityType: typ, EntityID: entity, Op: op}
+	}
+	type want struct {
+		event   int64
+		merged  []int64
+		version int64
+	}
+	tests := []struct {
+		name   string
+		events []models.Outbox
+		want   []want
+		folded int
+	}{
+		{
+			name:   "empty",
+			events: nil,
+		},
+		{
+			name:   "distinct entities",
+			events: []models.Outbox{ev(1, "user", a, "UPSERT"), ev(2, "user", b, "UPSERT")},
+			want:   []want{{1, []int64{1}, 1}, {2, []int64{2}, 2}},
+		},
+		{
+			name:   "last upsert wins",
+			events: []models.Outbox{ev(1, "user", a, "UPSERT"), ev(2, "user", a, "UPSERT"), ev(3, "user", a, "UPSERT")},
+			want:   []want{{3, []int64{1, 2, 3}, 3}},
+			folded: 2,
+		},
+		{
+			name:   "delete dominates a later upsert",
+			events: []models.Outbox{ev(1, "user", a, "UPSERT"), ev(2, "user", a, "DELETE"), ev(3, "user", a, "UPSERT")},
+			want:   []want{{2, []int64{1, 2, 3}, 3}},
+			folded: 2,
+		},
+		{
+			name:   "later delete replaces delete",
+			events: []models.Outbox{ev(1, "user", a, "DELETE"), ev(2, "user", a, "DELETE")},
+			want:   []want{{2, []int64{1, 2}, 2}},
+			folded: 1,
+		},
+		{
+			name:   "same id, different types",
+			events: []models.Outbox{ev(1, "user", a, "UPSERT"), ev(2, "project", a, "DELETE")},
+			want:   []want{{1, []int64{1}, 1}, {2, []int64{2}, 2}},
+		},
+		{
+			name: "ops keep the order of their first event",
+			events: []models.Outbox{ev(1, "user", a, "UPSERT"), ev(2, "user", b, "UPSERT"),
+				ev(3, "user", a, "UPSERT")},
+			want:   []want{{3, []int64{1, 3}, 3}, {2, []int64{2}, 2}},
+			folded: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, folded := coalesce(tt.events)
+			if folded != tt.folded {
+				t.Errorf("folded = %d, want %d", folded, tt.folded)
+			}
+			if len(ops) != len(tt.want) {
+				t.Fatalf("got %d ops, want %d", len(ops), len(tt.want))
+			}
+			for i, w := range tt.want {
+				op := ops[i]
+				if op.Event.ID != w.event || !slices.Equal(op.ids(), w.merged) || op.Version != w.version {
+					t.Errorf("op %d = event %d merged %v version %d, want event %d merged %v version %d",
+						i, op.Event.ID, op.ids(), op.Version, w.event, w.merged, w.version)
+				}
+			}
+		})
+	}
+}
+
+func TestEntityOpAttempts(t *testing.T) {
+	op := &entityOp{Merged: []models.Outbox{{ID: 1, Attempts: 2}, {ID: 2, Attempts: 4}, {ID: 3}}}
+	if got := op.attempts(); got != 4 {
+		t.Errorf("attempts() = %d, want 4", got)
+	}
+}
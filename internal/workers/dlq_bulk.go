@@ -0,0 +1,296 @@
+// internal/workers/dlq_bulk.go
+// this file retries, resolves or purges every DLQ row matching a filter, e.g. after an Elasticsearch outage
+package workers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DLQRetryAll   = "retry"
+	DLQResolveAll = "resolve"
+	DLQPurgeAll   = "purge"
+
+	dlqBulkPage           = 500
+	defaultDLQConcurrency = 2
+	MaxDLQConcurrency     = 8
+)
+
+var ErrEmptyDLQFilter = errors.New("purge needs at least one filter field")
+
+// DLQFilter selects DLQ rows; zero fields match everything.
+type DLQFilter struct {
+	EntityType  string     `json:"entity_type,omitempty"`
+	Op          string     `json:"op,omitempty"`
+	Category    string     `json:"category,omitempty"`
+	Fingerprint string     `json:"fingerprint,omitempty"`
+	Error       string     `json:"error,omitempty"` // case-insensitive substring of error_msg
+	Since       *time.Time `json:"since,omitempty"` // created_at >= since
+	Until       *time.Time `json:"until,omitempty"` // created_at < until
+	Resolved    *bool      `json:"resolved,omitempty"`
+}
+
+func (f DLQFilter) empty() bool { return f == DLQFilter{} }
+
+func (f DLQFilter) where(q *gorm.DB) *gorm.DB {
+	if f.EntityType != "" {
+		q = q.Where("entity_type = ?", f.EntityType)
+	}
+	if f.Op != "" {
+		q = q.Where("op = ?", f.Op)
+	}
+	if f.Category != "" {
+		q = q.Where("category = ?", f.Category)
+	}
+	if f.Fingerprint != "" {
+		q = q.Where("fingerprint = ?", f.Fingerprint)
+	}
+	if f.Error != "" {
+		q = q.Where("error_msg ILIKE '%' || ? || '%'", f.Error)
+	}
+	if f.Since != nil {
+		q = q.Where("created_at >= ?", *f.Since)
+	}
+	if f.Until != nil {
+		q = q.Where("created_at < ?", *f.Until)
+	}
+	if f.Resolved != nil {
+		q = q.Where("resolved = ?", *f.Resolved)
+	}
+	return q
+}
+
+// DLQBulkRequest is the body of the bulk DLQ endpoints.
+type DLQBulkRequest struct {
+	Action      string    `json:"action"`
+	Filter      DLQFilter `json:"filter"`
+	Concurrency int       `json:"concurrency,omitempty"` // bulk workers for retries, default 2, max 8
+}
+
+// DLQBulkResult summarises a finished bulk operation.
+type DLQBulkResult struct {
+	Action   string `json:"action"`
+	Matched  int64  `json:"matched"`
+	Resolved int64  `json:"resolved"`
+	Failed   int64  `json:"failed"` // retries that failed again (rescheduled or permanently failed)
+	Purged   int64  `json:"purged"`
+}
+
+// CountDLQ returns how many rows match f.
+func CountDLQ(ctx context.Context, db *gorm.DB, f DLQFilter) (int64, error) {
+	var n int64
+	err := f.where(db.WithContext(ctx).Model(&models.DLQ{})).Count(&n).Error
+	return n, err
+}
+
+// ResolveDLQRows marks every unresolved row matching f resolved without
+// retrying it, page by page so progress can be reported.
+func ResolveDLQRows(ctx context.Context, db *gorm.DB, f DLQFilter, progress func(n int64)) (DLQBulkResult, error) {
+	res := DLQBulkResult{Action: DLQResolveAll}
+	resolved := false
+	f.Resolved = &resolved
+	err := pageDLQ(ctx, db, f, func(ids []int64) error {
+		upd := db.WithContext(ctx).Model(&models.DLQ{}).Where("id IN ? AND resolved = false", ids).
+			Updates(map[string]any{"resolved": true, "next_retry_at": nil})
+		if upd.Error != nil {
+			return upd.Error
+		}
+		res.Matched += int64(len(ids))
+		res.Resolved += upd.RowsAffected
+		progress(int64(len(ids)))
+		return nil
+	})
+	return res, err
+}
+
+// PurgeDLQRows deletes every row matching f. An empty filter is refused.
+func PurgeDLQRows(ctx context.Context, db *gorm.DB, f DLQFilter, progress func(n int64)) (DLQBulkResult, error) {
+	res := DLQBulkResult{Action: DLQPurgeAll}
+	if f.empty() {
+		return res, ErrEmptyDLQFilter
+	}
+	err := pageDLQ(ctx, db, f, func(ids []int64) error {
+		var del *gorm.DB
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// the attempt history goes with its row
+			if err := tx.Where("dlq_id IN ?", ids).Delete(&models.DLQAttempt{}).Error; err != nil {
+				return err
+			}
+			del = tx.Where("id IN ?", ids).Delete(&models.DLQ{})
+			return del.Error
+		})
+		if err != nil {
+			return err
+		}
+		res.Matched += int64(len(ids))
+		res.Purged += del.RowsAffected
+		progress(int64(len(ids)))
+		return nil
+	})
+	return res, err
+}
+
+// pageDLQ calls fn with the ids of matching rows in id order, one page at a time.
+func pageDLQ(ctx context.Context, db *gorm.DB, f DLQFilter, fn func(ids []int64) error) error {
+	var after int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var ids []int64
+		q := f.where(db.WithContext(ctx).Model(&models.DLQ{})).Where("id > ?", after).Order("id").Limit(dlqBulkPage)
+		if err := q.Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := fn(ids); err != nil {
+			return err
+		}
+		after = ids[len(ids)-1]
+	}
+}
+
+// RetryDLQRows retries every unresolved row matching f, including rows the
+// scheduler gave up on, through a bulk indexer of its own with concurrency
+// workers. Each row counts as one retry attempt; outcomes are recorded
+// exactly as for scheduled retries. progress receives (resolved, failed)
+// deltas as Elasticsearch acknowledges them.
+func (w *SyncWorker) RetryDLQRows(ctx context.Context, f DLQFilter, concurrency int, progress func(ok, failed int64)) (DLQBulkResult, error) {
+	res := DLQBulkResult{Action: DLQRetryAll}
+	if concurrency <= 0 {
+		concurrency = defaultDLQConcurrency
+	}
+	concurrency = min(concurrency, MaxDLQConcurrency)
+
+	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Client:        w.ES,
+		NumWorkers:    concurrency,
+		FlushInterval: 2 * time.Second,
+	})
+	if err != nil {
+		return res, err
+	}
+
+	var resolved, failed atomic.Int64
+	acks := newAckTracker(w.DB, w.ID)
+	acks.onOutcome = func(_ *entityOp, ok bool, _ esutil.BulkIndexerResponseItem, _ string) {
+		if ok {
+			resolved.Add(1)
+			progress(1, 0)
+		} else {
+			failed.Add(1)
+			progress(0, 1)
+		}
+	}
+
+	unresolved := false
+	f.Resolved = &unresolved
+	var after int64
+	for err == nil {
+		var rows []models.DLQ
+		rows, err = claimDLQPage(ctx, w.DB, f, after, dlqBulkPage)
+		if err != nil || len(rows) == 0 {
+			break
+		}
+		after = rows[len(rows)-1].ID
+		res.Matched += int64(len(rows))
+
+		ops := make([]*entityOp, 0, len(rows))
+		for i := range rows {
+			d := &rows[i]
+			ob, evErr := w.dlqEvent(d)
+			if evErr != nil {
+				WriteDLQ(w.DB, DLQFailure{Event: models.Outbox{ID: d.OutboxID}, Retry: d, Category: CategoryNotFound, Message: evErr.Error(), WorkerID: w.ID})
+				failed.Add(1)
+				progress(0, 1)
+				continue
+			}
+			ops = append(ops, &entityOp{Event: ob, Merged: []models.Outbox{ob}, Version: ob.ID, Retry: d})
+		}
+		for op, applyErr := range w.apply(ctx, bi, acks, ops) {
+			WriteDLQ(w.DB, DLQFailure{Event: op.Event, Retry: op.Retry, Category: classifyError(applyErr), Message: applyErr.Error(), WorkerID: w.ID})
+			failed.Add(1)
+			progress(0, 1)
+		}
+		err = ctx.Err()
+	}
+	acks.seal()
+
+	// Close flushes and waits for every callback, so the counters are final
+	closeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if cerr := bi.Close(closeCtx); cerr != nil && err == nil {
+		err = cerr
+	}
+	res.Resolved, res.Failed = resolved.Load(), failed.Load()
+	return res, err
+}
+
+// claimDLQPage locks the next page of matching rows and pushes their
+// next_retry_at out, so the retry scheduler leaves them alone meanwhile.
+// Rows locked by a scheduler claim at this very moment are skipped.
+func claimDLQPage(ctx context.Context, db *gorm.DB, f DLQFilter, after int64, limit int) ([]models.DLQ, error) {
+	var rows []models.DLQ
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := f.where(tx.Model(&models.DLQ{})).
+			Where("id > ?", after).
+			Order("id").Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		if err := q.Find(&rows).Error; err != nil || len(rows) == 0 {
+			return err
+		}
+		ids := make([]int64, len(rows))
+		for i, r := range rows {
+			ids[i] = r.ID
+		}
+		return tx.Model(&models.DLQ{}).Where("id IN ?", ids).
+			Update("next_retry_at", gorm.Expr("now() + make_interval(secs => ?)", dlqRetryLease.Seconds())).Error
+	})
+	return rows, err
+}
+
+// DLQGroup is every DLQ row sharing one error fingerprint.
+type DLQGroup struct {
+	Fingerprint string         `json:"fingerprint"`
+	Category    string         `json:"category"`
+	Pattern     string         `json:"pattern"` // normalised error message
+	Count       int64          `json:"count"`
+	FirstSeen   time.Time      `json:"first_seen"`
+	LastSeen    time.Time      `json:"last_seen"`
+	EntityTypes datatypes.JSON `json:"entity_types"`
+	SampleIDs   datatypes.JSON `json:"sample_ids"` // newest DLQ ids, at most 5
+	SampleError string         `json:"sample_error"`
+}
+
+// GroupDLQ groups the rows matching f by fingerprint, largest group first.
+func GroupDLQ(ctx context.Context, db *gorm.DB, f DLQFilter, limit int) ([]DLQGroup, error) {
+	var groups []DLQGroup
+	err := f.where(db.WithContext(ctx).Model(&models.DLQ{})).
+		Select(`fingerprint,
+			min(category) AS category,
+			count(*) AS count,
+			min(created_at) AS first_seen,
+			max(created_at) AS last_seen,
+			json_agg(DISTINCT entity_type) AS entity_types,
+			to_json((array_agg(id ORDER BY id DESC))[1:5]) AS sample_ids,
+			(array_agg(error_msg ORDER BY id DESC))[1] AS sample_error`).
+		Group("fingerprint").
+		Order("count DESC, last_seen DESC").
+		Limit(limit).
+		Scan(&groups).Error
+	for i := range groups {
+		groups[i].Pattern = models.NormalizeError(groups[i].SampleError)
+	}
+	return groups, err
+}
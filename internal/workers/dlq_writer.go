@@ -0,0 +1,175 @@
+// internal/workers/dlq_writer.go
+// this file writes DLQ rows for single events: new failures, failed retries and resolutions (bulk resolve/purge live in dlq_bulk.go, retry claims in repo.go)
+package workers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/sirdesai22/sync-service/internal/metrics"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// DLQFailure is everything known about a failed event.
+type DLQFailure struct {
+	Event    models.Outbox
+	Retry    *models.DLQ // the row being retried; nil for a first failure
+	Category ErrorCategory
+	Message  string
+	Document []byte                          // body sent to Elasticsearch, nil for deletes or if never built
+	Response *esutil.BulkIndexerResponseItem // nil when Elasticsearch never answered
+	WorkerID string
+}
+
+// WriteDLQ records f. A first failure inserts a DLQ row scheduled with the
+// policy of its category; a failed retry updates the row it came from and
+// either reschedules it or, once attempts run out, marks it permanently
+// failed. Either way an attempt is appended to dlq_attempts.
+func WriteDLQ(db *gorm.DB, f DLQFailure) {
+	if f.Retry == nil {
+		insertDLQ(db, f)
+	} else {
+		rescheduleDLQ(db, f)
+	}
+}
+
+func insertDLQ(db *gorm.DB, f DLQFailure) {
+	metrics.DLQEvents.Inc()
+	ob, cat := f.Event, string(f.Category)
+	policy := policyFor(cat)
+	row, _ := json.Marshal(ob)
+	dlq := models.DLQ{
+		OutboxID:   ob.ID,
+		EntityType: ob.EntityType,
+		EntityID:   ob.EntityID.String(),
+		Op:         ob.Op,
+		ErrorMsg:   f.Message,
+		Payload:    ob.Payload,
+		CreatedAt:  time.Now(),
+		Resolved:   false,
+
+		Category:    cat,
+		Fingerprint: models.ErrorFingerprint(cat, f.Message),
+		OutboxRow:   datatypes.JSON(row),
+	}
+	setFailureContext(&dlq, f)
+	if policy.MaxAttempts > 0 {
+		next := time.Now().Add(policy.Backoff(1))
+		dlq.NextRetryAt = &next
+	} else {
+		dlq.PermanentlyFailed = true
+	}
+	dlqLogger.Printf("adding to DLQ outbox_id=%d entity=%s op=%s category=%s reason=%s", ob.ID, ob.EntityType, ob.Op, cat, f.Message)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&dlq).Error; err != nil {
+			return err
+		}
+		return tx.Create(newAttempt(dlq.ID, 0, models.DLQAttemptFailed, f)).Error
+	})
+	if err != nil {
+		dlqLogger.Printf("failed to insert into DLQ outbox_id=%d: %v", ob.ID, err)
+	} else {
+		dlqLogger.Printf("record created for outbox_id=%d entity=%s", ob.ID, ob.EntityType)
+	}
+}
+
+func rescheduleDLQ(db *gorm.DB, f DLQFailure) {
+	d, cat := f.Retry, string(f.Category)
+	policy := policyFor(cat)
+	attempts := d.Attempts + 1
+	var latest models.DLQ
+	setFailureContext(&latest, f)
+	updates := map[string]any{
+		"category":      cat,
+		"fingerprint":   models.ErrorFingerprint(cat, f.Message),
+		"attempts":      attempts,
+		"error_msg":     f.Message,
+		"retried_at":    time.Now(),
+		"document":      latest.Document,
+		"es_status":     latest.ESStatus,
+		"es_error_type": latest.ESErrorType,
+		"es_reason":     latest.ESReason,
+		"es_caused_by":  latest.ESCausedBy,
+		"worker_id":     latest.WorkerID,
+	}
+	if policy.Exhausted(attempts) {
+		metrics.DLQExhausted.Inc()
+		updates["permanently_failed"] = true
+		updates["next_retry_at"] = nil
+		dlqLogger.Printf("giving up on id=%d outbox_id=%d after %d attempts: %s", d.ID, d.OutboxID, attempts, f.Message)
+	} else {
+		wait := policy.Backoff(attempts + 1)
+		updates["next_retry_at"] = time.Now().Add(wait)
+		updates["permanently_failed"] = false
+		dlqLogger.Printf("retry %d/%d failed for id=%d outbox_id=%d, next in %s: %s", attempts, policy.MaxAttempts, d.ID, d.OutboxID, wait.Round(time.Second), f.Message)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DLQ{}).Where("id = ?", d.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Create(newAttempt(d.ID, attempts, models.DLQAttemptFailed, f)).Error
+	})
+	if err != nil {
+		dlqLogger.Printf("failed to reschedule id=%d: %v", d.ID, err)
+	}
+}
+
+// ResolveDLQ marks a row resolved once its retry was acknowledged with res.
+func ResolveDLQ(db *gorm.DB, d models.DLQ, workerID string, res esutil.BulkIndexerResponseItem) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.DLQ{}).Where("id = ?", d.ID).Updates(map[string]any{
+			"resolved":      true,
+			"attempts":      d.Attempts + 1,
+			"retried_at":    time.Now(),
+			"next_retry_at": nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(newAttempt(d.ID, d.Attempts+1, models.DLQAttemptResolved, DLQFailure{Response: &res, WorkerID: workerID})).Error
+	})
+}
+
+func setFailureContext(d *models.DLQ, f DLQFailure) {
+	if f.Document != nil {
+		d.Document = datatypes.JSON(f.Document)
+	}
+	d.WorkerID = f.WorkerID
+	if r := f.Response; r != nil {
+		d.ESStatus = r.Status
+		d.ESErrorType = r.Error.Type
+		d.ESReason = r.Error.Reason
+		d.ESCausedBy = causedBy(*r)
+	}
+}
+
+func newAttempt(dlqID int64, attempt int, outcome string, f DLQFailure) *models.DLQAttempt {
+	a := &models.DLQAttempt{
+		DLQID:     dlqID,
+		Attempt:   attempt,
+		Outcome:   outcome,
+		Category:  string(f.Category),
+		ErrorMsg:  f.Message,
+		WorkerID:  f.WorkerID,
+		CreatedAt: time.Now(),
+	}
+	if f.Document != nil {
+		a.Document = datatypes.JSON(f.Document)
+	}
+	if r := f.Response; r != nil {
+		a.ESStatus, a.ESResult = r.Status, r.Result
+		a.ESErrorType, a.ESReason, a.ESCausedBy = r.Error.Type, r.Error.Reason, causedBy(*r)
+	}
+	return a
+}
+
+func causedBy(r esutil.BulkIndexerResponseItem) string {
+	if r.Error.Cause.Type == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s", r.Error.Cause.Type, r.Error.Cause.Reason)
+}
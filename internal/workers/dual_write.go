@@ -0,0 +1,76 @@
+// internal/workers/dual_write.go
+// this file mirrors writes for an alias that is being migrated into its new index
+package workers
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/gorm"
+)
+
+// dualWriteRefresh is how stale a worker's view of active migrations may be.
+// The migrator waits twice this long after announcing a migration before it
+// starts backfilling, so no worker is still writing only to the old index.
+const dualWriteRefresh = 5 * time.Second
+
+// dualWrites caches, per alias, the new indices that writes must be mirrored to.
+type dualWrites struct {
+	db *gorm.DB
+
+	mu       sync.Mutex
+	targets  map[string][]string
+	loadedAt time.Time
+}
+
+func newDualWrites(db *gorm.DB) *dualWrites {
+	return &dualWrites{db: db}
+}
+
+func (d *dualWrites) targetsFor(ctx context.Context, alias string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if time.Since(d.loadedAt) > dualWriteRefresh {
+		var active []models.IndexMigration
+		err := d.db.WithContext(ctx).
+			Where("state IN ?", activeMigrationStates).
+			Find(&active).Error
+		if err != nil {
+			// keep mirroring to the last known targets rather than silently stopping
+			log.Printf("❌ failed to load active index migrations: %v", err)
+		} else {
+			d.targets = map[string][]string{}
+			for _, m := range active {
+				d.targets[m.Alias] = append(d.targets[m.Alias], m.ToIndex)
+			}
+		}
+		d.loadedAt = time.Now()
+	}
+	return d.targets[alias]
+}
+
+// mirrorItem writes op into a migration target. Its outcome does not affect
+// the outbox row, which is acknowledged by the primary write; a lost mirror
+// write shows up as a count mismatch when the migration verifies.
+func mirrorItem(op *entityOp, index, action string, body []byte) esutil.BulkIndexerItem {
+	version := op.Version
+	return esutil.BulkIndexerItem{
+		Action:      action,
+		Index:       index,
+		DocumentID:  op.Event.EntityID.String(),
+		Body:        bytes.NewReader(body),
+		Version:     &version,
+		VersionType: "external",
+		OnFailure: func(_ context.Context, _ esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
+			if err == nil && isVersionConflict(res) {
+				return
+			}
+			log.Printf("❌ dual-write to %s failed for outbox=%d: %s", index, op.Event.ID, bulkErrorMessage(res, err))
+		},
+	}
+}
@@ -0,0 +1,94 @@
+// internal/workers/handlers.go
+// this file registers the built-in user, hackathon and project handlers
+package workers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterEntity("user", ModelHandler[models.User]{
+		IndexName: elastic.IdxUsers,
+		ID:        func(u models.User) uuid.UUID { return u.ID },
+		BuildDoc:  elastic.BuildUserDoc,
+	})
+	RegisterEntity("hackathon", ModelHandler[models.Hackathon]{
+		IndexName: elastic.IdxHackathons,
+		ID:        func(h models.Hackathon) uuid.UUID { return h.ID },
+		BuildDoc:  elastic.BuildHackathonDoc,
+	})
+	RegisterEntity("project", projectHandler{})
+}
+
+// projectRow is a project joined with the rows its document embeds.
+type projectRow struct {
+	Project   models.Project
+	Owner     *models.User
+	Hackathon *models.Hackathon
+}
+
+// projectHandler denormalises owner and hackathon fields into project
+// documents. The join happens here, with one IN query per table for the whole
+// batch, rather than in SQL so each side stays a plain GORM model.
+type projectHandler struct{}
+
+func (projectHandler) Index() string        { return elastic.IdxProjects }
+func (projectHandler) DeleteAction() string { return "delete" }
+
+func (projectHandler) ListIDs(ctx context.Context, db *gorm.DB, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return listModelIDs[models.Project](ctx, db, after, limit)
+}
+
+func (projectHandler) Load(ctx context.Context, db *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]any, error) {
+	db = db.WithContext(ctx)
+	var projects []models.Project
+	if err := db.Where("id IN ?", ids).Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	if len(projects) == 0 {
+		return map[uuid.UUID]any{}, nil
+	}
+
+	ownerIDs := make([]uuid.UUID, 0, len(projects))
+	hackathonIDs := make([]uuid.UUID, 0, len(projects))
+	for _, p := range projects {
+		ownerIDs = append(ownerIDs, p.OwnerID)
+		hackathonIDs = append(hackathonIDs, p.HackathonID)
+	}
+	var owners []models.User
+	if err := db.Where("id IN ?", ownerIDs).Find(&owners).Error; err != nil {
+		return nil, err
+	}
+	var hackathons []models.Hackathon
+	if err := db.Where("id IN ?", hackathonIDs).Find(&hackathons).Error; err != nil {
+		return nil, err
+	}
+	ownerByID := make(map[uuid.UUID]*models.User, len(owners))
+	for i := range owners {
+		ownerByID[owners[i].ID] = &owners[i]
+	}
+	hackathonByID := make(map[uuid.UUID]*models.Hackathon, len(hackathons))
+	for i := range hackathons {
+		hackathonByID[hackathons[i].ID] = &hackathons[i]
+	}
+
+	out := make(map[uuid.UUID]any, len(projects))
+	for _, p := range projects {
+		out[p.ID] = projectRow{Project: p, Owner: ownerByID[p.OwnerID], Hackathon: hackathonByID[p.HackathonID]}
+	}
+	return out, nil
+}
+
+func (projectHandler) Build(row any) ([]byte, error) {
+	r, ok := row.(projectRow)
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected row type %T", elastic.IdxProjects, row)
+	}
+	return elastic.BuildProjectDoc(r.Project, r.Owner, r.Hackathon)
+}
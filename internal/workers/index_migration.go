@@ -0,0 +1,225 @@
+// internal/workers/index_migration.go
+// this file rebuilds an aliased index under a new version and swaps the alias without downtime
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/gorm"
+)
+
+type MigrateOptions struct {
+	Alias     string  `json:"alias"`
+	DeleteOld bool    `json:"delete_old"` // drop the previous index after the swap
+	Force     bool    `json:"force"`      // swap even when document counts differ
+	MaxRate   float64 `json:"max_rate"`   // backfill throttle, documents per second
+}
+
+// IndexMigrator performs a blue/green rebuild of one alias:
+//
+//  1. create <alias>_v(N+1) with the declared mapping
+//  2. record the migration so every sync worker dual-writes into it
+//  3. backfill it from Postgres with the Reindexer
+//  4. verify document counts against the live index
+//  5. atomically swap the alias and optionally delete the old index
+//
+// Searches keep hitting the old index through the alias until step 5.
+type IndexMigrator struct {
+	DB *gorm.DB
+	ES *es.Client
+
+	// OnProgress, when set, receives a line per step and backfill page.
+	OnProgress func(string)
+}
+
+func (m *IndexMigrator) Migrate(ctx context.Context, opts MigrateOptions) (models.IndexMigration, error) {
+	spec, ok := elastic.Spec(opts.Alias)
+	if !ok {
+		return models.IndexMigration{}, fmt.Errorf("unknown index alias %q", opts.Alias)
+	}
+	current, err := elastic.ResolveAlias(ctx, m.ES, spec.Alias)
+	if err != nil {
+		return models.IndexMigration{}, err
+	}
+	if len(current) != 1 {
+		return models.IndexMigration{}, fmt.Errorf("alias %s points at %d indices, expected exactly 1 (start the service once to create it)", spec.Alias, len(current))
+	}
+	from := current[0]
+
+	var active int64
+	if err := m.DB.WithContext(ctx).Model(&models.IndexMigration{}).
+		Where("alias = ? AND state IN ?", spec.Alias, activeMigrationStates).
+		Count(&active).Error; err != nil {
+		return models.IndexMigration{}, err
+	}
+	if active > 0 {
+		return models.IndexMigration{}, fmt.Errorf("alias %s already has a migration in progress (abort it with migrate-index --abort if its process is gone)", spec.Alias)
+	}
+
+	version := max(spec.Version, elastic.IndexVersion(spec.Alias, from)+1)
+	if version != spec.Version {
+		log.Printf("ℹ️ %s: declared v%d is not newer than %s; rebuilding as v%d with the declared mapping", spec.Alias, spec.Version, from, version)
+	}
+	to := elastic.VersionedName(spec.Alias, version)
+
+	// nothing is migrating into it, so an existing one is left over from a
+	// run that died before it could clean up
+	if err := elastic.DeleteIndexIfExists(ctx, m.ES, to); err != nil {
+		return models.IndexMigration{}, err
+	}
+	m.progress("creating %s", to)
+	if err := elastic.CreateIndex(ctx, m.ES, to, spec.Body); err != nil {
+		return models.IndexMigration{}, err
+	}
+
+	mig := models.IndexMigration{Alias: spec.Alias, FromIndex: from, ToIndex: to, State: models.MigrationDualWrite}
+	if err := m.DB.WithContext(ctx).Create(&mig).Error; err != nil {
+		return mig, err
+	}
+	if err := m.run(ctx, &mig, opts); err != nil {
+		if mig.State == models.MigrationSwapped {
+			// only deleting the old index failed; the migration itself is done
+			mig.Error = err.Error()
+			if saveErr := m.DB.Save(&mig).Error; saveErr != nil {
+				log.Printf("❌ failed to record migration error for %s: %v", spec.Alias, saveErr)
+			}
+			return mig, err
+		}
+		if failErr := m.fail(&mig, err.Error()); failErr != nil {
+			log.Printf("❌ failed to clean up migration of %s: %v", spec.Alias, failErr)
+		}
+		return mig, err
+	}
+	return mig, nil
+}
+
+var activeMigrationStates = []string{models.MigrationDualWrite, models.MigrationVerifying}
+
+// ErrNoActiveMigration is returned by Abort when the alias is not migrating.
+var ErrNoActiveMigration = errors.New("no migration in progress")
+
+// Abort fails the alias's dual_write or verifying migration, e.g. one whose
+// process crashed, and drops the index it was building so the alias can be
+// migrated again. The alias itself is untouched.
+func (m *IndexMigrator) Abort(ctx context.Context, alias string) (models.IndexMigration, error) {
+	var mig models.IndexMigration
+	err := m.DB.WithContext(ctx).Where("alias = ? AND state IN ?", alias, activeMigrationStates).
+		Order("id desc").First(&mig).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return mig, fmt.Errorf("%s: %w", alias, ErrNoActiveMigration)
+	}
+	if err != nil {
+		return mig, err
+	}
+	return mig, m.fail(&mig, "aborted")
+}
+
+// fail marks mig failed and deletes its target index once no worker mirrors
+// into it any more; a write arriving after the delete would recreate it with
+// a dynamic mapping. It runs on its own context so an interrupted migration
+// still cleans up.
+func (m *IndexMigrator) fail(mig *models.IndexMigration, reason string) error {
+	mig.State = models.MigrationFailed
+	mig.Error = reason
+	if err := m.DB.Save(mig).Error; err != nil {
+		return err
+	}
+	m.progress("%s -> %s failed (%s); dropping %s in %s", mig.FromIndex, mig.ToIndex, reason, mig.ToIndex, 2*dualWriteRefresh)
+	time.Sleep(2 * dualWriteRefresh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := elastic.DeleteIndexIfExists(ctx, m.ES, mig.ToIndex); err != nil {
+		return err
+	}
+	m.progress("deleted %s", mig.ToIndex)
+	return nil
+}
+
+func (m *IndexMigrator) run(ctx context.Context, mig *models.IndexMigration, opts MigrateOptions) error {
+	// give every worker time to pick up the dual-write before reading rows
+	m.progress("waiting %s for workers to start dual-writing into %s", 2*dualWriteRefresh, mig.ToIndex)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(2 * dualWriteRefresh):
+	}
+
+	types := entityTypesForIndex(mig.Alias)
+	if len(types) == 0 {
+		return errors.New("no registered entity type writes to this alias")
+	}
+	for _, t := range types {
+		m.progress("backfilling %s into %s", t, mig.ToIndex)
+		r := &Reindexer{DB: m.DB, ES: m.ES, OnProgress: func(p ReindexProgress) {
+			m.progress("backfill %s: indexed=%d skipped=%d failed=%d", t, p.Indexed, p.Skipped, p.Failed)
+		}}
+		p, err := r.Run(ctx, ReindexOptions{EntityType: t, Index: mig.ToIndex, MaxRate: opts.MaxRate})
+		if err != nil {
+			return fmt.Errorf("backfill %s: %w", t, err)
+		}
+		if p.Failed > 0 && !opts.Force {
+			return fmt.Errorf("backfill %s: %d documents failed (last: %s)", t, p.Failed, p.LastError)
+		}
+	}
+
+	mig.State = models.MigrationVerifying
+	if err := m.DB.Save(mig).Error; err != nil {
+		return err
+	}
+	var err error
+	if mig.FromCount, err = elastic.CountDocs(ctx, m.ES, mig.FromIndex); err != nil {
+		return err
+	}
+	if mig.ToCount, err = elastic.CountDocs(ctx, m.ES, mig.ToIndex); err != nil {
+		return err
+	}
+	m.progress("verify: %s=%d %s=%d", mig.FromIndex, mig.FromCount, mig.ToIndex, mig.ToCount)
+	if mig.FromCount != mig.ToCount && !opts.Force {
+		return fmt.Errorf("count mismatch: %s has %d documents, %s has %d (rerun with force to swap anyway)",
+			mig.FromIndex, mig.FromCount, mig.ToIndex, mig.ToCount)
+	}
+
+	if err := elastic.SwapAlias(ctx, m.ES, mig.Alias, mig.FromIndex, mig.ToIndex); err != nil {
+		return err
+	}
+	mig.State = models.MigrationSwapped
+	if err := m.DB.Save(mig).Error; err != nil {
+		return err
+	}
+	m.progress("alias %s now points at %s", mig.Alias, mig.ToIndex)
+
+	if opts.DeleteOld {
+		if err := elastic.DeleteIndex(ctx, m.ES, mig.FromIndex); err != nil {
+			return err
+		}
+		m.progress("deleted %s", mig.FromIndex)
+	}
+	return nil
+}
+
+func (m *IndexMigrator) progress(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("🔀 migrate-index: %s", msg)
+	if m.OnProgress != nil {
+		m.OnProgress(msg)
+	}
+}
+
+// entityTypesForIndex lists the registered entity types writing to alias.
+func entityTypesForIndex(alias string) []string {
+	var out []string
+	for _, t := range EntityTypes() {
+		if h, err := HandlerFor(t); err == nil && h.Index() == alias {
+			out = append(out, t)
+		}
+	}
+	return out
+}
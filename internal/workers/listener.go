@@ -0,0 +1,58 @@
+// internal/workers/listener.go
+// this file keeps a dedicated LISTEN connection open so the worker wakes as soon as outbox rows commit
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sirdesai22/sync-service/internal/db"
+	"github.com/sirdesai22/sync-service/internal/services"
+)
+
+const maxListenBackoff = 30 * time.Second
+
+// listen signals wake for every notification on services.OutboxChannel.
+// It reconnects with exponential backoff and signals once after each
+// (re)connect, so events committed while the listener was down are drained
+// right away instead of waiting for the fallback ticker.
+func (w *SyncWorker) listen(ctx context.Context, wake chan<- struct{}) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		conn, err := db.Listen(ctx, services.OutboxChannel)
+		if err != nil {
+			log.Printf("outbox listener connect failed: %v (retrying in %s)", err, backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxListenBackoff)
+			continue
+		}
+		backoff = time.Second
+		log.Printf("👂 Listening for outbox notifications on %q", services.OutboxChannel)
+		signal(wake)
+
+		for {
+			if _, err := conn.WaitForNotification(ctx); err != nil {
+				if ctx.Err() == nil {
+					log.Printf("outbox listener lost connection: %v", err)
+				}
+				break
+			}
+			signal(wake)
+		}
+		conn.Close(context.Background())
+	}
+}
+
+// signal performs a non-blocking send; one pending wakeup is enough because
+// the worker drains the outbox completely each time it wakes.
+func signal(wake chan<- struct{}) {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
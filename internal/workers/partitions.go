@@ -0,0 +1,151 @@
+// internal/workers/partitions.go
+// this file splits the outbox into hash partitions of entity_id and shares them between live worker instances
+package workers
+
+import (
+	"context"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirdesai22/sync-service/internal/metrics"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPartitionCount = 16
+	// memberTTL is how long an instance stays in the assignment without a
+	// heartbeat; three missed beats.
+	memberTTL = 3 * heartbeatInterval
+	// staleMemberAge is when a dead instance's row is deleted altogether.
+	staleMemberAge = 10 * time.Minute
+)
+
+// partitionExpr maps an outbox row to its partition. hashtext is stable
+// across processes and Postgres versions, and every event of one entity lands
+// in the same partition.
+const partitionExpr = "mod(hashtext(outboxes.entity_id::text)::bigint + 2147483648, ?)"
+
+// PartitionCount is the number of outbox partitions, SYNC_PARTITIONS. Every
+// instance must use the same value, and it caps how many instances can share
+// the work.
+var PartitionCount = sync.OnceValue(func() int {
+	v := os.Getenv("SYNC_PARTITIONS")
+	if v == "" {
+		return defaultPartitionCount
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		log.Printf("⚠️ invalid SYNC_PARTITIONS %q, using %d", v, defaultPartitionCount)
+		return defaultPartitionCount
+	}
+	return n
+})
+
+// Partitions tracks which outbox partitions one instance owns. Membership is
+// the worker_members table: each instance upserts its row on every refresh,
+// and the live instances, sorted by id, own partitions round robin. Joining
+// or leaving changes the list, so every instance picks up the new assignment
+// on its next refresh.
+type Partitions struct {
+	db    *gorm.DB
+	id    string
+	count int
+
+	mu         sync.RWMutex
+	owned      []int
+	members    []string
+	validUntil time.Time // the others drop this instance from the assignment after this
+}
+
+func newPartitions(db *gorm.DB, id string) *Partitions {
+	return &Partitions{db: db, id: id, count: PartitionCount()}
+}
+
+// Count is the total number of partitions.
+func (p *Partitions) Count() int { return p.count }
+
+// Owned returns the partitions this instance currently claims events from;
+// none once its membership has lapsed.
+func (p *Partitions) Owned() []int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if time.Now().After(p.validUntil) {
+		return nil
+	}
+	return p.owned
+}
+
+// Members returns the live instances as of the last refresh.
+func (p *Partitions) Members() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.members
+}
+
+// Refresh records a heartbeat for this instance and recomputes its
+// partitions from the live members; changed reports a rebalance. On error the
+// previous assignment is kept.
+func (p *Partitions) Refresh(ctx context.Context) (changed bool, err error) {
+	start := time.Now()
+	db := p.db.WithContext(ctx)
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.Assignments(map[string]any{"heartbeat": gorm.Expr("now()")}),
+	}).Create(&models.WorkerMember{ID: p.id, Heartbeat: time.Now(), StartedAt: time.Now()}).Error
+	if err != nil {
+		return false, err
+	}
+	if err := db.Where("heartbeat < now() - make_interval(secs => ?)", staleMemberAge.Seconds()).
+		Delete(&models.WorkerMember{}).Error; err != nil {
+		return false, err
+	}
+	var members []string
+	if err := db.Model(&models.WorkerMember{}).
+		Where("heartbeat >= now() - make_interval(secs => ?)", memberTTL.Seconds()).
+		Order("id").Pluck("id", &members).Error; err != nil {
+		return false, err
+	}
+
+	owned := assignPartitions(p.count, members, p.id)
+	p.mu.Lock()
+	changed = !slices.Equal(owned, p.owned) || !slices.Equal(members, p.members) || start.After(p.validUntil)
+	p.owned, p.members = owned, members
+	p.validUntil = start.Add(memberTTL)
+	p.mu.Unlock()
+
+	metrics.OwnedPartitions.Set(float64(len(owned)))
+	metrics.WorkerMembers.Set(float64(len(members)))
+	return changed, nil
+}
+
+// Leave removes this instance from the membership so the others take over
+// its partitions on their next refresh instead of after memberTTL.
+func (p *Partitions) Leave() {
+	if err := p.db.Delete(&models.WorkerMember{ID: p.id}).Error; err != nil {
+		log.Printf("❌ failed to leave worker membership as %s: %v", p.id, err)
+	}
+	p.mu.Lock()
+	p.owned, p.members, p.validUntil = nil, nil, time.Time{}
+	p.mu.Unlock()
+	metrics.OwnedPartitions.Set(0)
+}
+
+// assignPartitions gives partition i to the i-th member modulo the member
+// count; members must be sorted so every instance computes the same result.
+func assignPartitions(count int, members []string, id string) []int {
+	idx := slices.Index(members, id)
+	if idx < 0 {
+		return nil
+	}
+	var owned []int
+	for i := idx; i < count; i += len(members) {
+		owned = append(owned, i)
+	}
+	return owned
+}
@@ -0,0 +1,72 @@
+package workers
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAssignPartitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		count   int
+		members []string
+		id      string
+		want    []int
+	}{
+		{"alone", 4, []string{"a"}, "a", []int{0, 1, 2, 3}},
+		{"first of two", 5, []string{"a", "b"}, "a", []int{0, 2, 4}},
+		{"second of two", 5, []string{"a", "b"}, "b", []int{1, 3}},
+		{"last of three", 8, []string{"a", "b", "c"}, "c", []int{2, 5}},
+		{"more members than partitions", 2, []string{"a", "b", "c"}, "c", nil},
+		{"not a member", 4, []string{"a", "b"}, "z", nil},
+		{"no members", 4, nil, "a", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assignPartitions(tt.count, tt.members, tt.id); !slices.Equal(got, tt.want) {
+				t.Errorf("assignPartitions(%d, %v, %q) = %v, want %v", tt.count, tt.members, tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+// Every partition has exactly one owner, whatever the membership.
+func TestAssignPartitionsCoversAll(t *testing.T) {
+	members := []string{"a", "b", "c", "d", "e"}
+	for count := 1; count <= 20; count++ {
+		for n := 1; n <= len(members); n++ {
+			owners := make([]int, count)
+			for _, m := range members[:n] {
+				for _, p := range assignPartitions(count, members[:n], m) {
+					owners[p]++
+				}
+			}
+			for p, o := range owners {
+				if o != 1 {
+					t.Fatalf("count=%d members=%d: partition %d has %d owners", count, n, p, o)
+				}
+			}
+		}
+	}
+}
+
+func TestPartitionsOwnedLapses(t *testing.T) {
+	tests := []struct {
+		name       string
+		validUntil time.Time
+		want       []int
+	}{
+		{"valid", time.Now().Add(time.Minute), []int{1, 3}},
+		{"lapsed", time.Now().Add(-time.Second), nil},
+		{"never refreshed", time.Time{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Partitions{owned: []int{1, 3}, validUntil: tt.validUntil}
+			if got := p.Owned(); !slices.Equal(got, tt.want) {
+				t.Errorf("Owned() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// internal/workers/reconcile.go
+// this file compares every synced table with its index and reports (and optionally repairs) the differences
+package workers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/google/uuid"
+	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"github.com/sirdesai22/sync-service/internal/services"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultReconcileChunk = 500
+	reconcileSampleSize   = 20
+)
+
+type ReconcileOptions struct {
+	EntityType string `json:"entity_type"`
+	ChunkSize  int    `json:"chunk_size"` // ids per page, default 500
+	Repair     bool   `json:"repair"`     // enqueue outbox events for every difference
+}
+
+// ReconcileSamples lists the first few ids per kind of difference.
+type ReconcileSamples struct {
+	Missing  []string `json:"missing"`
+	Orphaned []string `json:"orphaned"`
+	Stale    []string `json:"stale"`
+}
+
+// Reconciler proves (or disproves) that an index matches Postgres. It walks
+// the table in id order comparing each row's freshly built document with the
+// indexed one by content hash, then walks the index for documents whose row
+// no longer exists. Repairs go through the outbox like any other change.
+type Reconciler struct {
+	DB *gorm.DB
+	ES *es.Client
+
+	// OnProgress, when set, is called after every page with the running report.
+	OnProgress func(models.ReconcileReport)
+}
+
+func (r *Reconciler) Run(ctx context.Context, opts ReconcileOptions) (models.ReconcileReport, error) {
+	report := models.ReconcileReport{EntityType: opts.EntityType, StartedAt: time.Now()}
+	h, err := HandlerFor(opts.EntityType)
+	if err != nil {
+		return report, err
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultReconcileChunk
+	}
+	report.Index = h.Index()
+
+	var samples ReconcileSamples
+	runErr := r.forward(ctx, h, opts, &report, &samples)
+	if runErr == nil {
+		runErr = r.orphans(ctx, h, opts, &report, &samples)
+	}
+
+	now := time.Now()
+	report.FinishedAt = &now
+	report.Samples, _ = json.Marshal(samples)
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	if err := r.DB.Create(&report).Error; err != nil {
+		log.Printf("❌ failed to save reconcile report for %s: %v", opts.EntityType, err)
+	}
+	log.Printf("🔍 reconcile %s: scanned=%d documents=%d missing=%d orphaned=%d stale=%d repaired=%d unrepairable=%d",
+		report.EntityType, report.Scanned, report.Documents, report.Missing, report.Orphaned, report.Stale, report.Repaired, report.Unrepairable)
+	if opts.Repair && report.Unrepairable > 0 {
+		log.Printf("⚠️ reconcile %s: %d orphaned documents left in place, the type keeps documents on delete (on_delete=ignore)", report.EntityType, report.Unrepairable)
+	}
+	return report, runErr
+}
+
+// forward pages through Postgres and flags rows that are missing or stale.
+func (r *Reconciler) forward(ctx context.Context, h EntityHandler, opts ReconcileOptions, report *models.ReconcileReport, samples *ReconcileSamples) error {
+	after := uuid.Nil
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ids, err := h.ListIDs(ctx, r.DB, after, opts.ChunkSize)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		after = ids[len(ids)-1]
+		rows, err := h.Load(ctx, r.DB, ids)
+		if err != nil {
+			return err
+		}
+		docIDs := make([]string, len(ids))
+		for i, id := range ids {
+			docIDs[i] = id.String()
+		}
+		indexed, err := elastic.MultiGet(ctx, r.ES, h.Index(), docIDs)
+		if err != nil {
+			return err
+		}
+
+		var repair []uuid.UUID
+		for _, id := range ids {
+			row, ok := rows[id]
+			if !ok {
+				continue // deleted since ListIDs
+			}
+			report.Scanned++
+			want, err := h.Build(row)
+			if err != nil {
+				return fmt.Errorf("build %s: %w", id, err)
+			}
+			got, ok := indexed[id.String()]
+			switch {
+			case !ok:
+				report.Missing++
+				samples.Missing = sample(samples.Missing, id.String())
+				repair = append(repair, id)
+			case contentHash(want) != contentHash(got):
+				report.Stale++
+				samples.Stale = sample(samples.Stale, id.String())
+				repair = append(repair, id)
+			}
+		}
+		if err := r.repair(opts, report, "UPSERT", repair); err != nil {
+			return err
+		}
+		if r.OnProgress != nil {
+			r.OnProgress(*report)
+		}
+	}
+}
+
+// orphans pages through the index and flags documents without a row.
+func (r *Reconciler) orphans(ctx context.Context, h EntityHandler, opts ReconcileOptions, report *models.ReconcileReport, samples *ReconcileSamples) error {
+	return elastic.ScanIDs(ctx, r.ES, h.Index(), opts.ChunkSize, func(docIDs []string) error {
+		report.Documents += int64(len(docIDs))
+		ids := make([]uuid.UUID, 0, len(docIDs))
+		var orphans []uuid.UUID
+		for _, d := range docIDs {
+			id, err := uuid.Parse(d)
+			if err != nil {
+				// not written by us; report it but never auto-delete it
+				report.Orphaned++
+				samples.Orphaned = sample(samples.Orphaned, d)
+				continue
+			}
+			ids = append(ids, id)
+		}
+		rows, err := h.Load(ctx, r.DB, ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if _, ok := rows[id]; !ok {
+				report.Orphaned++
+				samples.Orphaned = sample(samples.Orphaned, id.String())
+				orphans = append(orphans, id)
+			}
+		}
+		if h.DeleteAction() == "" {
+			// DELETE events are no-ops for this type; repairing would only
+			// enqueue the same orphans again on every run
+			report.Unrepairable += int64(len(orphans))
+			orphans = nil
+		}
+		if err := r.repair(opts, report, "DELETE", orphans); err != nil {
+			return err
+		}
+		if r.OnProgress != nil {
+			r.OnProgress(*report)
+		}
+		return nil
+	})
+}
+
+func (r *Reconciler) repair(opts ReconcileOptions, report *models.ReconcileReport, op string, ids []uuid.UUID) error {
+	if !opts.Repair || len(ids) == 0 {
+		return nil
+	}
+	if err := services.AddBatchOutboxEvents(r.DB, opts.EntityType, op, ids); err != nil {
+		return fmt.Errorf("enqueue repairs: %w", err)
+	}
+	report.Repaired += int64(len(ids))
+	return nil
+}
+
+// ReconcileAll reconciles every registered entity type in turn.
+func (r *Reconciler) ReconcileAll(ctx context.Context, repair bool) []models.ReconcileReport {
+	var reports []models.ReconcileReport
+	for _, t := range EntityTypes() {
+		rep, err := r.Run(ctx, ReconcileOptions{EntityType: t, Repair: repair})
+		if err != nil {
+			log.Printf("❌ reconcile %s failed: %v", t, err)
+		}
+		reports = append(reports, rep)
+	}
+	return reports
+}
+
+// Schedule runs ReconcileAll every interval until ctx is done.
+func (r *Reconciler) Schedule(ctx context.Context, interval time.Duration, repair bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.ReconcileAll(ctx, repair)
+		}
+	}
+}
+
+// contentHash hashes a document in canonical form (sorted keys, no
+// whitespace) so a byte-for-byte different but equal _source still matches.
+func contentHash(doc []byte) [32]byte {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return sha256.Sum256(doc)
+	}
+	canonical, _ := json.Marshal(v)
+	return sha256.Sum256(canonical)
+}
+
+func sample(list []string, id string) []string {
+	if len(list) >= reconcileSampleSize {
+		return list
+	}
+	return append(list, id)
+}
+
+// LatestReconcileReports returns the newest report per entity type.
+func LatestReconcileReports(db *gorm.DB) ([]models.ReconcileReport, error) {
+	var reports []models.ReconcileReport
+	err := db.Raw(`
+		SELECT DISTINCT ON (entity_type) * FROM reconcile_reports
+		ORDER BY entity_type, id DESC`).Scan(&reports).Error
+	return reports, err
+}
@@ -0,0 +1,123 @@
+// internal/workers/registry.go
+// this file holds the entity handler registry the sync worker dispatches outbox events through
+package workers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EntityHandler teaches the sync worker how to mirror one entity type into
+// Elasticsearch. Packages outside workers register their own handlers with
+// RegisterEntity; outbox rows are routed by their entity_type.
+type EntityHandler interface {
+	// Index is the Elasticsearch index (or alias) documents are written to.
+	Index() string
+	// Load fetches the rows for ids, ideally in a single query. Ids without a
+	// row are simply absent from the result.
+	Load(ctx context.Context, db *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]any, error)
+	// Build renders one row returned by Load as a document body.
+	Build(row any) ([]byte, error)
+	// DeleteAction is the bulk action used for DELETE events: "delete" removes
+	// the document, an empty string leaves it in place.
+	DeleteAction() string
+	// ListIDs pages through every row in primary key order, returning up to
+	// limit ids greater than after (uuid.Nil for the first page). Used by
+	// full reindexes and reconciliation.
+	ListIDs(ctx context.Context, db *gorm.DB, after uuid.UUID, limit int) ([]uuid.UUID, error)
+}
+
+var (
+	handlersMu sync.RWMutex
+	handlers   = map[string]EntityHandler{}
+)
+
+// RegisterEntity makes h responsible for outbox events of entityType. Like
+// database/sql.Register it panics on duplicates, since two handlers for one
+// type is a wiring bug.
+func RegisterEntity(entityType string, h EntityHandler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	if h == nil {
+		panic("workers: RegisterEntity handler is nil for " + entityType)
+	}
+	if _, dup := handlers[entityType]; dup {
+		panic("workers: RegisterEntity called twice for " + entityType)
+	}
+	handlers[entityType] = h
+}
+
+// HandlerFor returns the handler registered for entityType.
+func HandlerFor(entityType string) (EntityHandler, error) {
+	handlersMu.RLock()
+	h, ok := handlers[entityType]
+	handlersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown entity_type=%s (registered: %s)", entityType, strings.Join(EntityTypes(), ", "))
+	}
+	return h, nil
+}
+
+// EntityTypes lists the registered entity types in sorted order.
+func EntityTypes() []string {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	types := make([]string, 0, len(handlers))
+	for t := range handlers {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// ModelHandler is an EntityHandler for a GORM model whose primary key is a
+// uuid "id" column, which covers most synced tables.
+type ModelHandler[T any] struct {
+	IndexName string
+	ID        func(T) uuid.UUID
+	BuildDoc  func(T) ([]byte, error)
+}
+
+func (h ModelHandler[T]) Index() string        { return h.IndexName }
+func (h ModelHandler[T]) DeleteAction() string { return "delete" }
+
+func (h ModelHandler[T]) Load(ctx context.Context, db *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]any, error) {
+	var rows []T
+	if err := db.WithContext(ctx).Where("id IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]any, len(rows))
+	for _, r := range rows {
+		out[h.ID(r)] = r
+	}
+	return out, nil
+}
+
+func (h ModelHandler[T]) ListIDs(ctx context.Context, db *gorm.DB, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return listModelIDs[T](ctx, db, after, limit)
+}
+
+// listModelIDs is the keyset page query for models keyed by a uuid "id".
+func listModelIDs[T any](ctx context.Context, db *gorm.DB, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := db.WithContext(ctx).Model(new(T)).
+		Where("id > ?", after).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (h ModelHandler[T]) Build(row any) ([]byte, error) {
+	r, ok := row.(T)
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected row type %T", h.IndexName, row)
+	}
+	return h.BuildDoc(r)
+}
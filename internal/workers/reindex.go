@@ -0,0 +1,367 @@
+// internal/workers/reindex.go
+// this file rebuilds an index from Postgres by streaming every row through the entity handler
+package workers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/google/uuid"
+	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultReindexChunk = 500
+	reindexSnapshotPoll = 100 * time.Millisecond
+)
+
+type ReindexOptions struct {
+	EntityType string  `json:"entity_type"`
+	ChunkSize  int     `json:"chunk_size"` // rows per keyset page, default 500
+	MaxRate    float64 `json:"max_rate"`   // documents per second, 0 = unthrottled
+	Resume     bool    `json:"resume"`     // continue from the stored checkpoint
+	Index      string  `json:"index"`      // target index, defaults to the handler's
+}
+
+type ReindexProgress struct {
+	EntityType string    `json:"entity_type"`
+	Scanned    int64     `json:"scanned"`
+	Indexed    int64     `json:"indexed"`
+	Skipped    int64     `json:"skipped"` // deleted between page and load, or already newer
+	Failed     int64     `json:"failed"`
+	LastID     uuid.UUID `json:"last_id"` // last checkpointed id
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// Reindexer backfills an index straight from Postgres, bypassing the outbox.
+// Rows are paged by primary key, built by the entity handler and sent through
+// one bulk indexer; the checkpoint only advances past a page once every item
+// of it (and of all earlier pages) has been acknowledged.
+type Reindexer struct {
+	DB *gorm.DB
+	ES *es.Client
+
+	// OnProgress, when set, is called after every acknowledged page.
+	OnProgress func(ReindexProgress)
+}
+
+// reindexPage tracks the outstanding bulk items of one keyset page.
+type reindexPage struct {
+	lastID  uuid.UUID
+	pending int
+	sealed  bool
+}
+
+type reindexRun struct {
+	r      *Reindexer
+	opts   ReindexOptions
+	target string // checkpoint key next to the entity type, see ReindexCheckpoint
+	start  time.Time
+
+	mu       sync.Mutex
+	progress ReindexProgress
+	pages    []*reindexPage
+}
+
+func (r *Reindexer) Run(ctx context.Context, opts ReindexOptions) (ReindexProgress, error) {
+	h, err := HandlerFor(opts.EntityType)
+	if err != nil {
+		return ReindexProgress{}, err
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultReindexChunk
+	}
+	if opts.Index == "" {
+		opts.Index = h.Index()
+	}
+
+	run := &reindexRun{r: r, opts: opts, start: time.Now(), progress: ReindexProgress{EntityType: opts.EntityType}}
+	// a backfill into another index (e.g. a migration) keeps its own cursor
+	if opts.Index != h.Index() {
+		run.target = opts.Index
+	}
+	if err := run.loadCheckpoint(ctx); err != nil {
+		return run.progress, err
+	}
+
+	version, err := r.snapshotVersion(ctx)
+	if err != nil {
+		return run.progress, err
+	}
+
+	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Client:        r.ES,
+		NumWorkers:    2,
+		FlushBytes:    5 << 20,
+		FlushInterval: time.Second,
+	})
+	if err != nil {
+		return run.progress, err
+	}
+
+	log.Printf("🔄 reindex %s -> %s starting after id=%s", opts.EntityType, opts.Index, run.progress.LastID)
+	runErr := run.stream(ctx, h, bi, version)
+
+	// Close flushes whatever is buffered; use a fresh context so a cancelled
+	// run still gets its in-flight pages acknowledged and checkpointed.
+	closeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := bi.Close(closeCtx); err != nil && runErr == nil {
+		runErr = fmt.Errorf("flush bulk indexer: %w", err)
+	}
+
+	run.mu.Lock()
+	final := run.progress
+	run.mu.Unlock()
+	if runErr == nil {
+		if err := run.finish(); err != nil {
+			runErr = err
+		}
+	}
+	log.Printf("🏁 reindex %s: scanned=%d indexed=%d skipped=%d failed=%d in %s",
+		opts.EntityType, final.Scanned, final.Indexed, final.Skipped, final.Failed, time.Since(run.start).Round(time.Millisecond))
+	return final, runErr
+}
+
+// snapshotVersion returns the external version for reindexed documents: the
+// outbox id sequence's current value, once every transaction that was in
+// flight when it was read has finished. Any event with an id at or below it
+// is then committed (or gone) before a row is read, so its change is in the
+// document, and every event issued later carries a greater id and still wins.
+func (r *Reindexer) snapshotVersion(ctx context.Context) (int64, error) {
+	var version, xmax int64
+	db := r.DB.WithContext(ctx)
+	if err := db.Raw("SELECT COALESCE(pg_sequence_last_value(pg_get_serial_sequence('outboxes', 'id')::regclass), 0)").
+		Scan(&version).Error; err != nil {
+		return 0, err
+	}
+	if err := db.Raw("SELECT pg_snapshot_xmax(pg_current_snapshot())::text::bigint").Scan(&xmax).Error; err != nil {
+		return 0, err
+	}
+	for waited := time.Duration(0); ; waited += reindexSnapshotPoll {
+		var xmin int64
+		if err := db.Raw("SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint").Scan(&xmin).Error; err != nil {
+			return 0, err
+		}
+		if xmin >= xmax {
+			return version, nil
+		}
+		if waited > 0 && waited%(10*time.Second) == 0 {
+			log.Printf("⏳ reindex waiting for transactions older than xid %d to finish (%s)", xmax, waited)
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(reindexSnapshotPoll):
+		}
+	}
+}
+
+func (run *reindexRun) stream(ctx context.Context, h EntityHandler, bi esutil.BulkIndexer, version int64) error {
+	db := run.r.DB
+	after := run.progress.LastID
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ids, err := h.ListIDs(ctx, db, after, run.opts.ChunkSize)
+		if err != nil {
+			return fmt.Errorf("list ids after %s: %w", after, err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		rows, err := h.Load(ctx, db, ids)
+		if err != nil {
+			return fmt.Errorf("load page after %s: %w", after, err)
+		}
+		after = ids[len(ids)-1]
+
+		page := &reindexPage{lastID: after}
+		run.mu.Lock()
+		run.pages = append(run.pages, page)
+		run.progress.Scanned += int64(len(ids))
+		run.progress.Skipped += int64(len(ids) - len(rows))
+		run.mu.Unlock()
+
+		for _, id := range ids {
+			row, ok := rows[id]
+			if !ok {
+				continue // deleted since ListIDs
+			}
+			doc, err := h.Build(row)
+			if err != nil {
+				run.failed(page, fmt.Errorf("build %s: %w", id, err), true)
+				continue
+			}
+			if err := bi.Add(ctx, run.item(page, id, doc, version)); err != nil {
+				run.failed(page, err, true)
+				return err
+			}
+		}
+		run.seal(page)
+		run.throttle(ctx)
+	}
+}
+
+func (run *reindexRun) item(page *reindexPage, id uuid.UUID, doc []byte, version int64) esutil.BulkIndexerItem {
+	run.mu.Lock()
+	page.pending++
+	run.mu.Unlock()
+	return esutil.BulkIndexerItem{
+		Action:       "index",
+		Index:        run.opts.Index,
+		DocumentID:   id.String(),
+		Body:         bytes.NewReader(doc),
+		Version:      &version,
+		VersionType:  "external",
+		RequireAlias: elastic.IsManagedAlias(run.opts.Index),
+		OnSuccess: func(_ context.Context, _ esutil.BulkIndexerItem, _ esutil.BulkIndexerResponseItem) {
+			run.settle(page, func(p *ReindexProgress) { p.Indexed++ })
+		},
+		OnFailure: func(_ context.Context, _ esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
+			if err == nil && isVersionConflict(res) {
+				run.settle(page, func(p *ReindexProgress) { p.Skipped++ })
+				return
+			}
+			run.failed(page, fmt.Errorf("%s: %s", id, bulkErrorMessage(res, err)), false)
+		},
+	}
+}
+
+// failed records a document that could not be indexed. counted is true when
+// the item never reached the bulk indexer, so there is nothing to settle.
+func (run *reindexRun) failed(page *reindexPage, err error, counted bool) {
+	update := func(p *ReindexProgress) {
+		p.Failed++
+		p.LastError = err.Error()
+	}
+	if counted {
+		run.mu.Lock()
+		update(&run.progress)
+		run.mu.Unlock()
+		return
+	}
+	run.settle(page, update)
+}
+
+func (run *reindexRun) settle(page *reindexPage, update func(*ReindexProgress)) {
+	run.mu.Lock()
+	update(&run.progress)
+	page.pending--
+	run.mu.Unlock()
+	run.advance()
+}
+
+func (run *reindexRun) seal(page *reindexPage) {
+	run.mu.Lock()
+	page.sealed = true
+	run.mu.Unlock()
+	run.advance()
+}
+
+// advance checkpoints past every leading page that is fully acknowledged.
+func (run *reindexRun) advance() {
+	p, ok := run.popAcked()
+	if !ok {
+		return
+	}
+	if err := run.saveCheckpoint(p, nil); err != nil {
+		log.Printf("❌ reindex %s: checkpoint failed: %v", p.EntityType, err)
+	}
+	log.Printf("🔄 reindex %s: scanned=%d indexed=%d skipped=%d failed=%d last_id=%s",
+		p.EntityType, p.Scanned, p.Indexed, p.Skipped, p.Failed, p.LastID)
+	if run.r.OnProgress != nil {
+		run.r.OnProgress(p)
+	}
+}
+
+// popAcked drops the leading pages that are sealed with nothing pending and
+// moves LastID to the last of them. A page acknowledged ahead of an earlier
+// one stays queued, since the checkpoint must not skip the earlier page.
+func (run *reindexRun) popAcked() (ReindexProgress, bool) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	var done *reindexPage
+	for len(run.pages) > 0 && run.pages[0].sealed && run.pages[0].pending == 0 {
+		done = run.pages[0]
+		run.pages = run.pages[1:]
+	}
+	if done == nil {
+		return ReindexProgress{}, false
+	}
+	run.progress.LastID = done.lastID
+	return run.progress, true
+}
+
+// throttle sleeps long enough to keep the run at or below MaxRate docs/sec.
+func (run *reindexRun) throttle(ctx context.Context) {
+	if run.opts.MaxRate <= 0 {
+		return
+	}
+	run.mu.Lock()
+	scanned := run.progress.Scanned
+	run.mu.Unlock()
+	due := run.start.Add(time.Duration(float64(scanned) / run.opts.MaxRate * float64(time.Second)))
+	if wait := time.Until(due); wait > 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (run *reindexRun) loadCheckpoint(ctx context.Context) error {
+	if !run.opts.Resume {
+		return nil
+	}
+	var cp models.ReindexCheckpoint
+	err := run.r.DB.WithContext(ctx).First(&cp, "entity_type = ? AND target_index = ?", run.opts.EntityType, run.target).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if cp.FinishedAt != nil {
+		log.Printf("ℹ️ reindex %s: previous run finished at %s, starting over", run.opts.EntityType, cp.FinishedAt.Format(time.RFC3339))
+		return nil
+	}
+	run.progress.LastID = cp.LastID
+	run.progress.Indexed = cp.Indexed
+	run.progress.Failed = cp.Failed
+	run.start = time.Now()
+	return nil
+}
+
+func (run *reindexRun) finish() error {
+	run.mu.Lock()
+	p := run.progress
+	run.mu.Unlock()
+	now := time.Now()
+	return run.saveCheckpoint(p, &now)
+}
+
+func (run *reindexRun) saveCheckpoint(p ReindexProgress, finishedAt *time.Time) error {
+	cp := models.ReindexCheckpoint{
+		EntityType:  p.EntityType,
+		TargetIndex: run.target,
+		LastID:      p.LastID,
+		Indexed:     p.Indexed,
+		Failed:      p.Failed,
+		StartedAt:   run.start,
+		UpdatedAt:   time.Now(),
+		FinishedAt:  finishedAt,
+	}
+	return run.r.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&cp).Error
+}
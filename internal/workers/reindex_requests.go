@@ -0,0 +1,63 @@
+// internal/workers/reindex_requests.go
+// this file serves ReindexRequests, e.g. from cascades too large to enqueue event by event
+package workers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sirdesai22/sync-service/internal/models"
+)
+
+const reindexRequestPoll = 30 * time.Second
+
+// ServeRequests reindexes every entity type with open ReindexRequests, checking
+// every reindexRequestPoll until ctx is done. It is meant to run on the leader
+// only. A failed reindex leaves its requests open for the next check.
+func (r *Reindexer) ServeRequests(ctx context.Context) {
+	ticker := time.NewTicker(reindexRequestPoll)
+	defer ticker.Stop()
+	for {
+		r.serveOpenRequests(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reindexer) serveOpenRequests(ctx context.Context) {
+	var open []struct {
+		EntityType string
+		MaxID      int64
+	}
+	err := r.DB.WithContext(ctx).Model(&models.ReindexRequest{}).
+		Select("entity_type, max(id) AS max_id").Where("done_at IS NULL").
+		Group("entity_type").Scan(&open).Error
+	if err != nil {
+		log.Printf("❌ failed to load reindex requests: %v", err)
+		return
+	}
+	for _, o := range open {
+		if ctx.Err() != nil {
+			return
+		}
+		// requests up to MaxID are committed, so the run's snapshot covers them
+		log.Printf("🔄 serving reindex requests for %s (up to #%d)", o.EntityType, o.MaxID)
+		p, err := r.Run(ctx, ReindexOptions{EntityType: o.EntityType})
+		if err != nil {
+			log.Printf("❌ requested reindex of %s failed, retrying later: %v", o.EntityType, err)
+			continue
+		}
+		if p.Failed > 0 {
+			log.Printf("⚠️ requested reindex of %s: %d documents failed (last: %s)", o.EntityType, p.Failed, p.LastError)
+		}
+		if err := r.DB.Model(&models.ReindexRequest{}).
+			Where("entity_type = ? AND id <= ? AND done_at IS NULL", o.EntityType, o.MaxID).
+			Update("done_at", time.Now()).Error; err != nil {
+			log.Printf("❌ failed to mark reindex requests for %s done: %v", o.EntityType, err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package workers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestReindexPopAcked(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	type page struct {
+		sealed  bool
+		pending int
+	}
+	tests := []struct {
+		name   string
+		pages  []page
+		ok     bool
+		lastID uuid.UUID
+		left   int
+	}{
+		{"no pages", nil, false, uuid.Nil, 0},
+		{"first still pending", []page{{true, 1}, {true, 0}, {true, 0}}, false, uuid.Nil, 3},
+		{"first not sealed", []page{{false, 0}, {true, 0}}, false, uuid.Nil, 2},
+		{"first acked", []page{{true, 0}, {true, 2}, {true, 0}}, true, ids[0], 2},
+		{"leading run acked", []page{{true, 0}, {true, 0}, {false, 0}}, true, ids[1], 1},
+		{"all acked", []page{{true, 0}, {true, 0}, {true, 0}}, true, ids[2], 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &reindexRun{}
+			for i, p := range tt.pages {
+				run.pages = append(run.pages, &reindexPage{lastID: ids[i], sealed: p.sealed, pending: p.pending})
+			}
+			p, ok := run.popAcked()
+			if ok != tt.ok || run.progress.LastID != tt.lastID || len(run.pages) != tt.left {
+				t.Errorf("popAcked() ok=%v last_id=%s left=%d, want ok=%v last_id=%s left=%d",
+					ok, run.progress.LastID, len(run.pages), tt.ok, tt.lastID, tt.left)
+			}
+			if ok && p.LastID != tt.lastID {
+				t.Errorf("returned progress last_id=%s, want %s", p.LastID, tt.lastID)
+			}
+		})
+	}
+}
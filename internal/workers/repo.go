@@ -1,14 +1,15 @@
 // internal/workers/repo.go
-// this file is used to fetch the outbox events and put them in the DLQ
+// this file is used to fetch and acknowledge outbox events and to claim DLQ rows for retry
 package workers
 
 import (
 	"context"
 	"log"
 	"os"
+	"sort"
 	"time"
 
-	"github.com/sirdesai22/sync-service/internal/metrics"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
 	"github.com/sirdesai22/sync-service/internal/models"
 	"gorm.io/gorm"
 )
@@ -17,41 +18,153 @@ var dlqLogger = log.New(os.Stdout, "[DLQ] ", log.LstdFlags)
 
 type OutboxBatch struct{ Events []models.Outbox }
 
-func FetchOutboxBatch(ctx context.Context, db *gorm.DB, limit int) (OutboxBatch, error) {
+// ClaimOutboxBatch leases up to limit events to workerID for the given lease
+// duration. Pending rows are eligible, and so are in-flight rows whose lease
+// has expired (their worker crashed or never got an Elasticsearch response),
+// so no event is lost between claim and acknowledgement.
+//
+// Only rows in the owned partitions (out of count, see partitionExpr) are
+// considered, and an entity is skipped while another worker still holds a
+// live lease on one of its events, so after a rebalance the new owner waits
+// for the previous one instead of racing it.
+func ClaimOutboxBatch(ctx context.Context, db *gorm.DB, workerID string, limit int, lease time.Duration, owned []int, count int) (OutboxBatch, error) {
+	if len(owned) == 0 {
+		return OutboxBatch{}, nil
+	}
 	var evts []models.Outbox
-	// FOR UPDATE SKIP LOCKED to allow multiple workers later
+	// FOR UPDATE SKIP LOCKED so concurrent workers never claim the same row
 	tx := db.WithContext(ctx).Raw(`
 		WITH cte AS (
-		  SELECT * FROM outboxes
-		  WHERE processed = false
+		  SELECT id FROM outboxes
+		  WHERE (status = ? OR (status = ? AND lease_expires_at < now()))
+		    AND `+partitionExpr+` IN ?
+		    AND NOT EXISTS (
+		      SELECT 1 FROM outboxes held
+		      WHERE held.entity_type = outboxes.entity_type
+		        AND held.entity_id = outboxes.entity_id
+		        AND held.status = ? AND held.lease_expires_at >= now()
+		        AND held.claimed_by <> ?
+		    )
 		  ORDER BY id ASC
 		  LIMIT ?
 		  FOR UPDATE SKIP LOCKED
 		)
-		UPDATE outboxes SET processed = true
+		UPDATE outboxes SET
+		  status = ?,
+		  claimed_by = ?,
+		  lease_expires_at = now() + make_interval(secs => ?),
+		  attempts = outboxes.attempts + 1
 		FROM cte
 		WHERE outboxes.id = cte.id
-		RETURNING cte.*`, limit).Scan(&evts)
+		RETURNING outboxes.*`,
+		models.OutboxPending, models.OutboxInFlight, count, owned,
+		models.OutboxInFlight, workerID, limit,
+		models.OutboxInFlight, workerID, lease.Seconds()).Scan(&evts)
+	// RETURNING does not preserve the CTE order
+	sort.Slice(evts, func(i, j int) bool { return evts[i].ID < evts[j].ID })
 	return OutboxBatch{Events: evts}, tx.Error
 }
 
-// PutDLQ inserts a failed outbox event into the DLQ table.
-func PutDLQ(db *gorm.DB, ob models.Outbox, msg string) {
-	metrics.DLQEvents.Inc()
-	dlq := models.DLQ{
-		OutboxID:   ob.ID,
-		EntityType: ob.EntityType,
-		EntityID:   ob.EntityID.String(),
-		Op:         ob.Op,
-		ErrorMsg:   msg,
-		Payload:    ob.Payload,
-		CreatedAt:  time.Now(),
-		Resolved:   false,
-	}
-	dlqLogger.Printf("adding to DLQ outbox_id=%d entity=%s op=%s reason=%s", ob.ID, ob.EntityType, ob.Op, msg)
-	if err := db.Create(&dlq).Error; err != nil {
-		dlqLogger.Printf("failed to insert into DLQ outbox_id=%d: %v", ob.ID, err)
-	} else {
-		dlqLogger.Printf("record created for outbox_id=%d entity=%s", ob.ID, ob.EntityType)
+// AckOutbox finalises rows with the result Elasticsearch reported for them.
+func AckOutbox(db *gorm.DB, ids []int64, status string, res esutil.BulkIndexerResponseItem) error {
+	return db.Model(&models.Outbox{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{
+			"status":           status,
+			"lease_expires_at": nil,
+			"es_status":        res.Status,
+			"es_result":        res.Result,
+			"es_seq_no":        res.SeqNo,
+			"es_version":       res.Version,
+			"acked_at":         time.Now(),
+		}).Error
+}
+
+// MarkOutboxFailed finalises rows that were handed to the DLQ before ever
+// reaching Elasticsearch.
+func MarkOutboxFailed(db *gorm.DB, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
 	}
+	return db.Model(&models.Outbox{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{"status": models.OutboxFailed, "lease_expires_at": nil}).Error
+}
+
+// ClaimDueDLQ returns up to limit unresolved rows whose next retry is due and
+// pushes their next_retry_at out by lease, so another instance does not pick
+// them up while this one waits for Elasticsearch. If no outcome is recorded
+// (crash) they simply become due again.
+func ClaimDueDLQ(ctx context.Context, db *gorm.DB, limit int, lease time.Duration) ([]models.DLQ, error) {
+	var rows []models.DLQ
+	err := db.WithContext(ctx).Raw(`
+		WITH cte AS (
+		  SELECT id FROM dlqs
+		  WHERE resolved = false AND permanently_failed = false
+		    AND (next_retry_at IS NULL OR next_retry_at <= now())
+		  ORDER BY next_retry_at NULLS FIRST, id
+		  LIMIT ?
+		  FOR UPDATE SKIP LOCKED
+		)
+		UPDATE dlqs SET next_retry_at = now() + make_interval(secs => ?)
+		FROM cte
+		WHERE dlqs.id = cte.id
+		RETURNING dlqs.*`, limit, lease.Seconds()).Scan(&rows).Error
+	sort.Slice(rows, func(i, j int) bool { return rows[i].ID < rows[j].ID })
+	return rows, err
+}
+
+// DeferOutbox hands claimed rows back for another attempt after delay by
+// letting their lease expire then, instead of finalising them.
+func DeferOutbox(db *gorm.DB, ids []int64, delay time.Duration) error {
+	return db.Model(&models.Outbox{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{
+			"status":           models.OutboxInFlight,
+			"lease_expires_at": time.Now().Add(delay),
+		}).Error
+}
+
+// ReleaseClaims hands every in-flight row claimed by workerID back to
+// pending. The claim is not counted as an attempt, so restarts alone never
+// push an event towards the DLQ.
+func ReleaseClaims(db *gorm.DB, workerID string) (int64, error) {
+	res := db.Model(&models.Outbox{}).
+		Where("status = ? AND claimed_by = ?", models.OutboxInFlight, workerID).
+		Updates(map[string]any{
+			"status":           models.OutboxPending,
+			"lease_expires_at": nil,
+			"attempts":         gorm.Expr("GREATEST(attempts - 1, 0)"),
+		})
+	return res.RowsAffected, res.Error
+}
+
+func countClaims(db *gorm.DB, workerID string) (int64, error) {
+	var n int64
+	err := db.Model(&models.Outbox{}).
+		Where("status = ? AND claimed_by = ?", models.OutboxInFlight, workerID).
+		Count(&n).Error
+	return n, err
+}
+
+// OutboxBacklog counts unfinished outbox rows.
+type OutboxBacklog struct {
+	Pending       int64      `json:"pending"`
+	InFlight      int64      `json:"in_flight"`
+	Failed        int64      `json:"failed"`
+	OldestPending *time.Time `json:"oldest_pending,omitempty"`
+}
+
+func GetOutboxBacklog(ctx context.Context, db *gorm.DB) (OutboxBacklog, error) {
+	var b OutboxBacklog
+	err := db.WithContext(ctx).Raw(`
+		SELECT
+		  count(*) FILTER (WHERE status = ?) AS pending,
+		  count(*) FILTER (WHERE status = ?) AS in_flight,
+		  count(*) FILTER (WHERE status = ?) AS failed,
+		  min(created_at) FILTER (WHERE status IN (?, ?)) AS oldest_pending
+		FROM outboxes WHERE status <> ?`,
+		models.OutboxPending, models.OutboxInFlight, models.OutboxFailed,
+		models.OutboxPending, models.OutboxInFlight, models.OutboxDone).Scan(&b).Error
+	return b, err
 }
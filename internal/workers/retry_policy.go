@@ -0,0 +1,142 @@
+// internal/workers/retry_policy.go
+// this file decides when a dead-lettered event is retried next, and when to give up
+package workers
+
+import (
+	"log"
+	"math"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy is an exponential backoff with jitter: retry n waits
+// InitialDelay * Multiplier^(n-1), capped at MaxDelay, then randomised by
+// ±Jitter (a fraction) so rows that failed together do not retry together.
+type RetryPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	Jitter       float64
+	MaxAttempts  int // 0 disables automatic retries
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		InitialDelay: 30 * time.Second,
+		MaxDelay:     time.Hour,
+		Multiplier:   2,
+		Jitter:       0.2,
+		MaxAttempts:  8,
+	}
+}
+
+// DefaultRetryPolicies gives each error category its own schedule. Transient
+// failures use the default; a missing row rarely comes back, a mapping
+// failure needs an operator (migrate-index) so it is retried slowly, and a
+// serialization failure is deterministic so it is never retried.
+func DefaultRetryPolicies() map[ErrorCategory]RetryPolicy {
+	base := DefaultRetryPolicy()
+	notFound, mapping, serialization := base, base, base
+	notFound.MaxAttempts = 2
+	mapping.InitialDelay, mapping.MaxDelay, mapping.MaxAttempts = 10*time.Minute, 6*time.Hour, 5
+	serialization.MaxAttempts = 0
+	return map[ErrorCategory]RetryPolicy{
+		CategoryTransient:     base,
+		CategoryNotFound:      notFound,
+		CategoryMapping:       mapping,
+		CategorySerialization: serialization,
+		CategoryUnknown:       base,
+	}
+}
+
+// RetryPoliciesFromEnv overrides the defaults with DLQ_RETRY_INITIAL_DELAY,
+// DLQ_RETRY_MAX_DELAY, DLQ_RETRY_MULTIPLIER, DLQ_RETRY_JITTER and
+// DLQ_RETRY_MAX_ATTEMPTS for every category, then with the same variables
+// infixed by category (e.g. DLQ_RETRY_MAPPING_MAX_ATTEMPTS) for one.
+// Invalid values are logged and ignored.
+func RetryPoliciesFromEnv() map[ErrorCategory]RetryPolicy {
+	policies := DefaultRetryPolicies()
+	for cat, p := range policies {
+		p.override("DLQ_RETRY_")
+		p.override("DLQ_RETRY_" + strings.ToUpper(string(cat)) + "_")
+		policies[cat] = p
+	}
+	return policies
+}
+
+func (p *RetryPolicy) override(prefix string) {
+	envDuration(prefix+"INITIAL_DELAY", &p.InitialDelay)
+	envDuration(prefix+"MAX_DELAY", &p.MaxDelay)
+	envFloat(prefix+"MULTIPLIER", &p.Multiplier)
+	envFloat(prefix+"JITTER", &p.Jitter)
+	if v := os.Getenv(prefix + "MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			p.MaxAttempts = n
+		} else {
+			log.Printf("⚠️ invalid %sMAX_ATTEMPTS %q, using %d", prefix, v, p.MaxAttempts)
+		}
+	}
+}
+
+// retryPolicies is read once, after main has loaded .env.
+var retryPolicies = sync.OnceValue(RetryPoliciesFromEnv)
+
+// policyFor returns the retry policy for cat; rows written before categories
+// existed count as unknown.
+func policyFor(cat string) RetryPolicy {
+	if p, ok := retryPolicies()[ErrorCategory(cat)]; ok {
+		return p
+	}
+	return retryPolicies()[CategoryUnknown]
+}
+
+// retriesEnabled reports whether any category is retried automatically.
+func retriesEnabled() bool {
+	for _, p := range retryPolicies() {
+		if p.MaxAttempts > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// inProcessRetry spaces out transient failures that are handed back to the
+// outbox (see deferOutbox) before they are given up to the DLQ.
+var inProcessRetry = RetryPolicy{InitialDelay: time.Second, MaxDelay: 30 * time.Second, Multiplier: 2, Jitter: 0.2, MaxAttempts: maxClaimAttempts}
+
+// Backoff returns the delay before retry attempt n (1-based).
+func (p RetryPolicy) Backoff(n int) time.Duration {
+	d := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(max(n-1, 0)))
+	d = min(d, float64(p.MaxDelay))
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// Exhausted reports whether a row that has failed attempts times is done.
+func (p RetryPolicy) Exhausted(attempts int) bool { return attempts >= p.MaxAttempts }
+
+func envDuration(key string, dst *time.Duration) {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			*dst = d
+		} else {
+			log.Printf("⚠️ invalid %s %q, using %s", key, v, *dst)
+		}
+	}
+}
+
+func envFloat(key string, dst *float64) {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+			*dst = f
+		} else {
+			log.Printf("⚠️ invalid %s %q, using %g", key, v, *dst)
+		}
+	}
+}
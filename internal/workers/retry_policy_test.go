@@ -0,0 +1,86 @@
+package workers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialDelay: time.Second, MaxDelay: 10 * time.Second, Multiplier: 2}
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.Backoff(tt.n); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	p := RetryPolicy{InitialDelay: 10 * time.Second, MaxDelay: time.Minute, Multiplier: 2, Jitter: 0.2}
+	for n := 1; n <= 5; n++ {
+		base := min(10*time.Second<<(n-1), time.Minute)
+		lo, hi := time.Duration(float64(base)*0.8), time.Duration(float64(base)*1.2)
+		for range 100 {
+			if got := p.Backoff(n); got < lo || got > hi {
+				t.Fatalf("Backoff(%d) = %s, want within [%s, %s]", n, got, lo, hi)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyExhausted(t *testing.T) {
+	tests := []struct {
+		max, attempts int
+		want          bool
+	}{
+		{0, 0, true},
+		{0, 1, true},
+		{2, 1, false},
+		{2, 2, true},
+		{8, 3, false},
+	}
+	for _, tt := range tests {
+		p := RetryPolicy{MaxAttempts: tt.max}
+		if got := p.Exhausted(tt.attempts); got != tt.want {
+			t.Errorf("MaxAttempts=%d Exhausted(%d) = %v, want %v", tt.max, tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPoliciesFromEnv(t *testing.T) {
+	t.Setenv("DLQ_RETRY_MAX_ATTEMPTS", "3")
+	t.Setenv("DLQ_RETRY_MAPPING_MAX_ATTEMPTS", "0")
+	t.Setenv("DLQ_RETRY_TRANSIENT_INITIAL_DELAY", "5s")
+	t.Setenv("DLQ_RETRY_JITTER", "-1") // invalid, keeps the default
+
+	policies := RetryPoliciesFromEnv()
+	tests := []struct {
+		cat         ErrorCategory
+		maxAttempts int
+		initial     time.Duration
+	}{
+		{CategoryTransient, 3, 5 * time.Second},
+		{CategoryNotFound, 3, 30 * time.Second},
+		{CategoryMapping, 0, 10 * time.Minute},
+		{CategorySerialization, 3, 30 * time.Second},
+		{CategoryUnknown, 3, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		p := policies[tt.cat]
+		if p.MaxAttempts != tt.maxAttempts || p.InitialDelay != tt.initial || p.Jitter != 0.2 {
+			t.Errorf("%s: got max_attempts=%d initial=%s jitter=%g, want %d %s 0.2",
+				tt.cat, p.MaxAttempts, p.InitialDelay, p.Jitter, tt.maxAttempts, tt.initial)
+		}
+	}
+}
@@ -1,17 +1,38 @@
+// internal/workers/retry_worker.go
+// this file re-applies dead-lettered events on an exponential backoff schedule
 package workers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8/esutil"
-	"github.com/sirdesai22/sync-service/internal/models"
+	"github.com/google/uuid"
 	"github.com/sirdesai22/sync-service/internal/metrics"
+	"github.com/sirdesai22/sync-service/internal/models"
+	"gorm.io/datatypes"
+)
+
+const (
+	dlqPollInterval = 10 * time.Second
+	dlqBatchSize    = 50
+	// dlqRetryLease keeps a claimed row from being retried again while its
+	// bulk item is still waiting to be flushed and acknowledged.
+	dlqRetryLease = 2 * time.Minute
 )
 
-func (w *SyncWorker) RetryDLQ(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
+// RetryDLQ retries due DLQ rows through bi until ctx is done; a row is only
+// resolved once Elasticsearch acknowledges it. It runs only on the elected
+// leader; other instances skip every poll.
+func (w *SyncWorker) RetryDLQ(ctx context.Context, bi esutil.BulkIndexer) {
+	for _, cat := range ErrorCategories {
+		p := policyFor(string(cat))
+		log.Printf("♻️ DLQ retries for %s: up to %d attempts, backoff %s..%s", cat, p.MaxAttempts, p.InitialDelay, p.MaxDelay)
+	}
+	ticker := time.NewTicker(dlqPollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -19,32 +40,116 @@ func (w *SyncWorker) RetryDLQ(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			var dlqs []models.DLQ
-			if err := w.DB.Where("resolved = false").Limit(50).Find(&dlqs).Error; err != nil {
-				log.Printf("DLQ fetch error: %v", err)
+			if !w.Leader.IsLeader() {
 				continue
 			}
-			for _, d := range dlqs {
-				log.Printf("♻️ Retrying DLQ id=%d entity=%s op=%s", d.ID, d.EntityType, d.Op)
-				ob := models.Outbox{
-					ID:         d.OutboxID,
-					EntityType: d.EntityType,
-					Op:         d.Op,
-				}
-				// reapply event
-				bi, _ := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
-					Client: w.ES, Index: "", FlushBytes: 5 << 20, NumWorkers: 2,
-				})
-				if err := w.applyEvent(ctx, bi, ob); err == nil {
-					now := time.Now()
-					w.DB.Model(&models.DLQ{}).Where("id = ?", d.ID).Updates(map[string]any{
-						"resolved":  true,
-						"retried_at": &now,
-					})
-					metrics.ProcessedEvents.Inc()
-					log.Printf("✅ DLQ id=%d resolved", d.ID)
-				}
+			if err := w.retryDue(ctx, bi); err != nil {
+				log.Printf("DLQ retry cycle failed: %v", err)
 			}
 		}
 	}
 }
+
+func (w *SyncWorker) retryDue(ctx context.Context, bi esutil.BulkIndexer) error {
+	rows, err := ClaimDueDLQ(ctx, w.DB, dlqBatchSize, dlqRetryLease)
+	if err != nil || len(rows) == 0 {
+		return err
+	}
+
+	ops := make([]*entityOp, 0, len(rows))
+	for i := range rows {
+		d := &rows[i]
+		ob, err := w.dlqEvent(d)
+		if err != nil {
+			WriteDLQ(w.DB, DLQFailure{Event: models.Outbox{ID: d.OutboxID}, Retry: d, Category: CategoryNotFound, Message: err.Error(), WorkerID: w.ID})
+			continue
+		}
+		log.Printf("♻️ Retrying DLQ id=%d entity=%s/%s op=%s attempt=%d", d.ID, d.EntityType, d.EntityID, d.Op, d.Attempts+1)
+		ops = append(ops, &entityOp{Event: ob, Merged: []models.Outbox{ob}, Version: ob.ID, Retry: d})
+	}
+	metrics.DLQRetries.Add(float64(len(ops)))
+
+	acks := newAckTracker(w.DB, w.ID)
+	for op, err := range w.apply(ctx, bi, acks, ops) {
+		cat := classifyError(err)
+		metrics.SyncErrors.WithLabelValues(string(cat)).Inc()
+		WriteDLQ(w.DB, DLQFailure{Event: op.Event, Retry: op.Retry, Category: cat, Message: err.Error(), WorkerID: w.ID})
+	}
+	acks.seal()
+	return nil
+}
+
+// ErrDLQResolved is returned when retrying a DLQ row that is already resolved.
+var ErrDLQResolved = errors.New("dlq row not found or already resolved")
+
+// DLQRetryResult is the outcome of a synchronous retry.
+type DLQRetryResult struct {
+	DLQID    int64                           `json:"dlq_id"`
+	Resolved bool                            `json:"resolved"`
+	Response *esutil.BulkIndexerResponseItem `json:"response,omitempty"` // nil if nothing reached Elasticsearch
+	Error    string                          `json:"error,omitempty"`
+}
+
+// RetryDLQEntry retries one DLQ row and waits for the answer: the item goes
+// through a bulk indexer of its own that is closed, i.e. flushed, before
+// returning. The row is resolved (or its failure recorded) by the usual ack
+// callbacks, so when this returns the row already reflects the result.
+func (w *SyncWorker) RetryDLQEntry(ctx context.Context, id int64) (DLQRetryResult, error) {
+	res := DLQRetryResult{DLQID: id}
+	var rows []models.DLQ
+	err := w.DB.WithContext(ctx).Raw(`
+		UPDATE dlqs SET next_retry_at = now() + make_interval(secs => ?)
+		WHERE id = ? AND resolved = false
+		RETURNING *`, dlqRetryLease.Seconds(), id).Scan(&rows).Error
+	if err != nil {
+		return res, err
+	}
+	if len(rows) == 0 {
+		return res, ErrDLQResolved
+	}
+	d := &rows[0]
+	log.Printf("♻️ Manually retrying DLQ id=%d entity=%s/%s op=%s", d.ID, d.EntityType, d.EntityID, d.Op)
+
+	ob, err := w.dlqEvent(d)
+	if err != nil {
+		WriteDLQ(w.DB, DLQFailure{Event: models.Outbox{ID: d.OutboxID}, Retry: d, Category: CategoryNotFound, Message: err.Error(), WorkerID: w.ID})
+		res.Error = err.Error()
+		return res, nil
+	}
+
+	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{Client: w.ES, NumWorkers: 1})
+	if err != nil {
+		return res, err
+	}
+	acks := newAckTracker(w.DB, w.ID)
+	acks.onOutcome = func(_ *entityOp, ok bool, item esutil.BulkIndexerResponseItem, msg string) {
+		res.Resolved, res.Response, res.Error = ok, &item, msg
+	}
+	op := &entityOp{Event: ob, Merged: []models.Outbox{ob}, Version: ob.ID, Retry: d}
+	if applyErr := w.apply(ctx, bi, acks, []*entityOp{op})[op]; applyErr != nil {
+		WriteDLQ(w.DB, DLQFailure{Event: ob, Retry: d, Category: classifyError(applyErr), Message: applyErr.Error(), WorkerID: w.ID})
+		res.Error = applyErr.Error()
+	}
+	acks.seal()
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := bi.Close(closeCtx); err != nil {
+		return res, fmt.Errorf("flush: %w", err)
+	}
+	return res, nil
+}
+
+// dlqEvent returns the outbox event behind d, rebuilding it from the DLQ
+// columns if the outbox row has since been cleaned up.
+func (w *SyncWorker) dlqEvent(d *models.DLQ) (models.Outbox, error) {
+	var ob models.Outbox
+	if err := w.DB.First(&ob, "id = ?", d.OutboxID).Error; err == nil {
+		return ob, nil
+	}
+	id, err := uuid.Parse(d.EntityID)
+	if err != nil {
+		return ob, err
+	}
+	return models.Outbox{ID: d.OutboxID, EntityType: d.EntityType, EntityID: id, Op: d.Op, Payload: datatypes.JSON(d.Payload)}, nil
+}
@@ -2,26 +2,94 @@
 package workers
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	// "github.com/google/uuid"
 	es "github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esutil"
-	"github.com/sirdesai22/sync-service/internal/elastic"
+	"github.com/google/uuid"
+	"github.com/sirdesai22/sync-service/internal/leader"
 	"github.com/sirdesai22/sync-service/internal/metrics"
 	"github.com/sirdesai22/sync-service/internal/models"
 	"gorm.io/gorm"
 )
 
+const (
+	batchSize = 200
+	// fallbackPollInterval is the safety net for missed notifications; the
+	// LISTEN connection normally wakes the worker long before this fires.
+	fallbackPollInterval = 30 * time.Second
+	// leaseDuration bounds how long a claimed event may wait for its
+	// Elasticsearch acknowledgement before another worker may reclaim it.
+	leaseDuration = time.Minute
+	// maxClaimAttempts stops an event that keeps losing its lease (e.g. it
+	// crashes the worker) from being reclaimed forever.
+	maxClaimAttempts = 5
+	// heartbeatInterval is how often the worker loop reports that it is alive
+	// while idle; a busy loop reports after every batch.
+	heartbeatInterval = 5 * time.Second
+	// defaultDrainTimeout fits inside the usual 30s termination grace period.
+	defaultDrainTimeout = 20 * time.Second
+)
+
 type SyncWorker struct {
 	DB *gorm.DB
 	ES *es.Client
+	ID string // identifies this instance in outboxes.claimed_by
+
+	// Leader gates the singleton jobs started by Run (DLQ retries); nil means
+	// this instance always runs them.
+	Leader *leader.Elector
+
+	// DrainTimeout bounds how long Run spends flushing the bulk indexer once
+	// its context is canceled.
+	DrainTimeout time.Duration
+
+	dual       *dualWrites
+	partitions *Partitions
+
+	heartbeat atomic.Int64 // unix nanos of the last loop iteration, 0 when not running
+	lastBatch atomic.Int64 // unix nanos of the last batch that claimed events
+}
+
+// Heartbeat is when the worker loop last showed it was alive; zero if the
+// worker is not running.
+func (w *SyncWorker) Heartbeat() time.Time { return unixNanoTime(w.heartbeat.Load()) }
+
+// Partitions is this instance's share of the outbox.
+func (w *SyncWorker) Partitions() *Partitions { return w.partitions }
+
+// LastBatch is when the worker last finished a batch that claimed events.
+func (w *SyncWorker) LastBatch() time.Time { return unixNanoTime(w.lastBatch.Load()) }
+
+func unixNanoTime(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+func NewSyncWorker(db *gorm.DB, es *es.Client) *SyncWorker {
+	id := instanceID()
+	return &SyncWorker{DB: db, ES: es, ID: id, DrainTimeout: defaultDrainTimeout, dual: newDualWrites(db), partitions: newPartitions(db, id)}
 }
 
+// instanceID returns a per-process identifier of the form host-pid.
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Run processes the outbox until ctx is canceled, then shuts down gracefully
+// (see shutdown) before returning.
 func (w *SyncWorker) Run(ctx context.Context) {
 	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
 		Client:        w.ES,
@@ -32,155 +100,291 @@ func (w *SyncWorker) Run(ctx context.Context) {
 		log.Fatalf("Bulk indexer init failed: %v", err)
 	}
 
+	// other instances rebalance as soon as this one has released its claims;
+	// deferred first so it runs after shutdown
+	defer w.partitions.Leave()
+	w.rebalance(ctx)
+
 	// ✅ Close only once, when the worker exits — not after every batch
-	defer func() {
-		log.Println("Closing BulkIndexer …")
-		if err := bi.Close(ctx); err != nil {
-			log.Printf("BulkIndexer close error: %v", err)
-		}
-	}()
+	defer w.shutdown(bi)
 
-	ticker := time.NewTicker(3 * time.Second)
+	// retries share this indexer, so they must stop before it is closed
+	var retries sync.WaitGroup
+	defer retries.Wait()
+	if retriesEnabled() {
+		retries.Go(func() { w.RetryDLQ(ctx, bi) })
+	}
+
+	wake := make(chan struct{}, 1)
+	go w.listen(ctx, wake)
+
+	// membership is refreshed on its own ticker so a long drain never lets it
+	// lapse; it must stop before Leave runs
+	var membership sync.WaitGroup
+	defer membership.Wait()
+	membership.Go(func() { w.keepMembership(ctx, wake) })
+
+	ticker := time.NewTicker(fallbackPollInterval)
 	defer ticker.Stop()
+	beat := time.NewTicker(heartbeatInterval)
+	defer beat.Stop()
+	w.heartbeat.Store(time.Now().UnixNano())
+	defer w.heartbeat.Store(0)
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Println("Sync worker shutting down")
 			return
+		case <-wake:
+			w.drain(ctx, bi) // pass the same BulkIndexer on every wakeup
 		case <-ticker.C:
-			w.processOnce(ctx, bi) // pass the same BulkIndexer each tick
+			w.drain(ctx, bi)
+		case <-beat.C:
 		}
+		w.heartbeat.Store(time.Now().UnixNano())
 	}
 }
 
-func (w *SyncWorker) processOnce(ctx context.Context, bi esutil.BulkIndexer) error {
-	batch, err := FetchOutboxBatch(ctx, w.DB, 200)
+// keepMembership refreshes the membership every heartbeatInterval until ctx
+// is done, waking the loop when newly owned partitions may have a backlog.
+func (w *SyncWorker) keepMembership(ctx context.Context, wake chan<- struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if w.rebalance(ctx) {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// rebalance refreshes this instance's membership and reports whether the
+// partitions it owns changed.
+func (w *SyncWorker) rebalance(ctx context.Context) bool {
+	changed, err := w.partitions.Refresh(ctx)
 	if err != nil {
-		return err
+		if ctx.Err() == nil {
+			log.Printf("❌ worker membership refresh failed (partitions are dropped once it lapses): %v", err)
+		}
+		return false
+	}
+	if changed {
+		metrics.Rebalances.Inc()
+		log.Printf("⚖️ %s owns %d/%d outbox partitions %v (%d live workers)",
+			w.ID, len(w.partitions.Owned()), w.partitions.Count(), w.partitions.Owned(), len(w.partitions.Members()))
+	}
+	return changed
+}
+
+// shutdown runs once Run's context is canceled and nothing is claimed any
+// more: it flushes the bulk indexer within DrainTimeout (with a fresh
+// context, since the old one is done) so buffered items are acknowledged,
+// then hands every claim still without an acknowledgement back to pending
+// so another instance picks it up right away instead of after its lease.
+func (w *SyncWorker) shutdown(bi esutil.BulkIndexer) {
+	claimed, _ := countClaims(w.DB, w.ID)
+	log.Printf("🛑 draining sync worker: %d claimed events awaiting acknowledgement, %d bulk items queued", claimed, bi.Stats().NumAdded-bi.Stats().NumFlushed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.DrainTimeout)
+	defer cancel()
+	if err := bi.Close(ctx); err != nil {
+		log.Printf("❌ BulkIndexer close error: %v", err)
+	}
+	stats := bi.Stats()
+
+	released, err := ReleaseClaims(w.DB, w.ID)
+	if err != nil {
+		log.Printf("❌ failed to release claims of %s: %v", w.ID, err)
+	}
+	log.Printf("🛑 sync worker stopped: %d claimed events acknowledged while draining, %d released back to pending (bulk: %d indexed, %d failed)",
+		max(claimed-released, 0), released, stats.NumIndexed+stats.NumDeleted, stats.NumFailed)
+}
+
+// drain processes batches until the outbox is empty, so a burst of
+// notifications costs one wakeup rather than one per event.
+func (w *SyncWorker) drain(ctx context.Context, bi esutil.BulkIndexer) {
+	for ctx.Err() == nil {
+		n, err := w.processOnce(ctx, bi)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("outbox batch failed: %v", err)
+			}
+			return
+		}
+		now := time.Now().UnixNano()
+		w.heartbeat.Store(now)
+		if n > 0 {
+			w.lastBatch.Store(now)
+		}
+		if n < batchSize {
+			return
+		}
+	}
+}
+
+func (w *SyncWorker) processOnce(ctx context.Context, bi esutil.BulkIndexer) (int, error) {
+	// Owned is re-read for every batch and is empty once the membership has
+	// not been refreshed within memberTTL, i.e. when the others may have
+	// taken the partitions over
+	batch, err := ClaimOutboxBatch(ctx, w.DB, w.ID, batchSize, leaseDuration, w.partitions.Owned(), w.partitions.Count())
+	if err != nil {
+		return 0, err
 	}
 	if len(batch.Events) == 0 {
-		return nil
+		return 0, nil
 	}
 
+	live := batch.Events[:0:0]
 	for _, e := range batch.Events {
-		if err := w.applyEvent(ctx, bi, e); err != nil {
-			// Put back to DLQ (already marked processed to avoid infinite loop)
-			metrics.FailedEvents.Inc()
-			PutDLQ(w.DB, e, err.Error())
-			log.Printf("DLQ outbox_id=%d: %v", e.ID, err)
+		if e.Attempts > maxClaimAttempts {
+			w.fail(e, []int64{e.ID}, CategoryUnknown, fmt.Sprintf("claimed %d times without acknowledgement", e.Attempts-1))
 			continue
 		}
-		metrics.ProcessedEvents.Inc()
+		live = append(live, e)
 	}
 
-	stats := bi.Stats()
-	log.Printf("bulk ok=%d failed=%d", stats.NumFlushed, stats.NumFailed)
-	return nil
+	ops, coalesced := coalesce(live)
+	if coalesced > 0 {
+		metrics.CoalescedEvents.Add(float64(coalesced))
+		log.Printf("🧩 coalesced %d events into %d operations", len(live), len(ops))
+	}
+
+	// success is only counted once Elasticsearch acknowledges each item
+	acks := newAckTracker(w.DB, w.ID)
+	for op, err := range w.apply(ctx, bi, acks, ops) {
+		if ctx.Err() != nil {
+			continue // shutting down: the claim is released, not failed
+		}
+		cat := classifyError(err)
+		if deferTransient(w.DB, op, cat, err.Error()) {
+			continue
+		}
+		w.fail(op.Event, op.ids(), cat, err.Error())
+	}
+	acks.seal()
+	return len(batch.Events), nil
 }
 
-func (w *SyncWorker) ApplyEvent(ctx context.Context, bi esutil.BulkIndexer, e models.Outbox) error {
-	return w.applyEvent(ctx, bi, e)
+// fail moves an op that never reached Elasticsearch to the DLQ; ids are all
+// the outbox rows folded into it.
+func (w *SyncWorker) fail(e models.Outbox, ids []int64, cat ErrorCategory, msg string) {
+	metrics.FailedEvents.Inc()
+	WriteDLQ(w.DB, DLQFailure{Event: e, Category: cat, Message: msg, WorkerID: w.ID})
+	if err := MarkOutboxFailed(w.DB, ids...); err != nil {
+		log.Printf("❌ failed to mark outbox_id=%d failed: %v", e.ID, err)
+	}
+	log.Printf("DLQ outbox_id=%d: %s", e.ID, msg)
 }
 
-func (w *SyncWorker) applyEvent(ctx context.Context, bi esutil.BulkIndexer, e models.Outbox) error {
-	switch e.EntityType {
-	case "user":
-		var u models.User
-		if e.Op == "DELETE" {
-			return w.add(bi, elastic.IdxUsers, e.EntityID.String(), e.ID, "delete", nil)
-		}
-		if err := w.DB.First(&u, "id = ?", e.EntityID).Error; err != nil {
-			return err
+// apply loads every surviving UPSERT with one query per entity type, then
+// hands each op to the bulk indexer. Ops that never reached the indexer are
+// returned with their error; the caller decides whether they go to the DLQ.
+func (w *SyncWorker) apply(ctx context.Context, bi esutil.BulkIndexer, acks *ackTracker, ops []*entityOp) map[*entityOp]error {
+	upserts := map[string][]uuid.UUID{}
+	for _, op := range ops {
+		if !op.isDelete() {
+			upserts[op.Event.EntityType] = append(upserts[op.Event.EntityType], op.Event.EntityID)
 		}
-		doc, err := elastic.BuildUserDoc(u)
+	}
+	rows := map[string]map[uuid.UUID]any{}
+	loadErrs := map[string]error{}
+	for entityType, ids := range upserts {
+		h, err := HandlerFor(entityType)
 		if err != nil {
-			return err
+			loadErrs[entityType] = err
+			continue
 		}
-		return w.add(bi, elastic.IdxUsers, e.EntityID.String(), e.ID, "index", doc)
+		rows[entityType], loadErrs[entityType] = h.Load(ctx, w.DB, ids)
+	}
 
-	case "hackathon":
-		var h models.Hackathon
-		if e.Op == "DELETE" {
-			return w.add(bi, elastic.IdxHackathons, e.EntityID.String(), e.ID, "delete", nil)
-		}
-		if err := w.DB.First(&h, "id = ?", e.EntityID).Error; err != nil {
-			return err
-		}
-		doc, err := elastic.BuildHackathonDoc(h)
-		if err != nil {
-			return err
+	failed := map[*entityOp]error{}
+	for _, op := range ops {
+		if err := w.applyOp(ctx, bi, acks, op, rows[op.Event.EntityType], loadErrs[op.Event.EntityType]); err != nil {
+			failed[op] = err
 		}
-		return w.add(bi, elastic.IdxHackathons, e.EntityID.String(), e.ID, "index", doc)
+	}
+	return failed
+}
 
-	case "project":
-		var p models.Project
-		if e.Op == "DELETE" {
-			return w.add(bi, elastic.IdxProjects, e.EntityID.String(), e.ID, "delete", nil)
-		}
-		if err := w.DB.First(&p, "id = ?", e.EntityID).Error; err != nil {
-			return err
-		}
-		doc, err := elastic.BuildProjectDoc(p)
-		if err != nil {
-			return err
+func (w *SyncWorker) applyOp(ctx context.Context, bi esutil.BulkIndexer, acks *ackTracker, op *entityOp, rows map[uuid.UUID]any, loadErr error) error {
+	h, err := HandlerFor(op.Event.EntityType)
+	if err != nil {
+		return err
+	}
+	if op.isDelete() {
+		action := h.DeleteAction()
+		if action == "" {
+			acks.skipped(op)
+			return nil
 		}
-		return w.add(bi, elastic.IdxProjects, e.EntityID.String(), e.ID, "index", doc)
+		return w.add(ctx, bi, acks, op, h.Index(), action, nil)
+	}
+	if loadErr != nil {
+		return loadErr
+	}
+	row, ok := rows[op.Event.EntityID]
+	if !ok {
+		return w.tombstone(ctx, bi, acks, op, h)
+	}
+	doc, err := h.Build(row)
+	if err != nil {
+		return err
 	}
-	return fmt.Errorf("unknown entity_type=%s", e.EntityType)
+	return w.add(ctx, bi, acks, op, h.Index(), "index", doc)
 }
 
-func (w *SyncWorker) add(bi esutil.BulkIndexer, index, docID string, outboxID int64, action string, body []byte) error {
-	item := esutil.BulkIndexerItem{
-		Action:     action,
-		Index:      index,
-		DocumentID: docID,
-		Body:       bytes.NewReader(body),
-		OnSuccess: func(_ context.Context, _ esutil.BulkIndexerItem, _ esutil.BulkIndexerResponseItem) {
-			log.Printf("✅ synced %s id=%s", index, docID)
-		},
-		OnFailure: func(_ context.Context, it esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
-			log.Printf("💀 OnFailure fired for %s/%s", it.Index, it.DocumentID)
-
-			msg := ""
-			if err != nil {
-				msg = err.Error()
-			} else if res.Error.Reason != "" {
-				msg = fmt.Sprintf("%s: %s", res.Error.Type, res.Error.Reason)
-			} else {
-				msg = fmt.Sprintf("status=%d", res.Status)
-			}
+// Tombstone policies for an UPSERT whose row no longer exists, i.e. it was
+// deleted after the event was written. Set with SYNC_TOMBSTONE_POLICY.
+const (
+	TombstoneDelete = "delete" // delete the document, as a DELETE event would (default)
+	TombstoneDLQ    = "dlq"    // fail the event with a not_found error
+	TombstoneSkip   = "skip"   // acknowledge the event without writing
+)
 
-			dbErr := w.DB.Session(&gorm.Session{}).Create(&models.DLQ{
-				OutboxID:   outboxID,
-				EntityType: indexToEntity(index),
-				EntityID:   docID,
-				Op:         action,
-				ErrorMsg:   msg,
-				CreatedAt:  time.Now(),
-				Resolved:   false,
-			}).Error
-			if dbErr != nil {
-				log.Printf("❌ DLQ insert failed: %v", dbErr)
-			} else {
-				log.Printf("💾 DLQ row added for outbox=%d", outboxID)
-			}
-		},
+var tombstonePolicy = sync.OnceValue(func() string {
+	switch p := os.Getenv("SYNC_TOMBSTONE_POLICY"); p {
+	case "":
+		return TombstoneDelete
+	case TombstoneDelete, TombstoneDLQ, TombstoneSkip:
+		return p
+	default:
+		log.Printf("⚠️ invalid SYNC_TOMBSTONE_POLICY %q, using %q", p, TombstoneDelete)
+		return TombstoneDelete
 	}
+})
 
-	log.Printf("💾 Adding item to Elasticsearch: %s %s %s", index, action, docID)
-	return bi.Add(context.Background(), item)
+func (w *SyncWorker) tombstone(ctx context.Context, bi esutil.BulkIndexer, acks *ackTracker, op *entityOp, h EntityHandler) error {
+	policy := tombstonePolicy()
+	action := h.DeleteAction()
+	if policy == TombstoneDLQ {
+		return fmt.Errorf("%s %s: %w", op.Event.EntityType, op.Event.EntityID, gorm.ErrRecordNotFound)
+	}
+	if policy == TombstoneSkip || action == "" {
+		acks.skipped(op)
+		return nil
+	}
+	log.Printf("🪦 %s %s no longer exists, deleting its document (outbox=%d)", op.Event.EntityType, op.Event.EntityID, op.Event.ID)
+	return w.add(ctx, bi, acks, op, h.Index(), action, nil)
 }
 
-func indexToEntity(index string) string {
-	switch index {
-	case elastic.IdxUsers:
-		return "user"
-	case elastic.IdxProjects:
-		return "project"
-	case elastic.IdxHackathons:
-		return "hackathon"
-	default:
-		return "unknown"
+func (w *SyncWorker) add(ctx context.Context, bi esutil.BulkIndexer, acks *ackTracker, op *entityOp, index, action string, body []byte) error {
+	log.Printf("💾 Adding item to Elasticsearch: %s %s %s", index, action, op.Event.EntityID)
+	if err := bi.Add(ctx, acks.item(op, index, action, body)); err != nil {
+		acks.dropped()
+		return err
 	}
+	for _, target := range w.dual.targetsFor(ctx, index) {
+		if err := bi.Add(ctx, mirrorItem(op, target, action, body)); err != nil {
+			log.Printf("❌ dual-write to %s not queued for outbox=%d: %v", target, op.Event.ID, err)
+		}
+	}
+	return nil
 }
@@ -42,16 +42,20 @@ export default function App() {
   const safeDlq: any[] = Array.isArray(dlq) ? dlq : [];
 
   const processedCount = safeOutbox.filter(
-    (item) => item.Processed ?? item.processed,
+    (item) => (item.Status ?? item.status) === "done",
   ).length;
   const pendingCount = safeOutbox.length - processedCount;
   const activeDlq = safeDlq.filter((item) => !(item.Resolved ?? item.resolved))
     .length;
 
   async function retry(id: string) {
-    await axios.get(`http://localhost:8080/api/retry/${id}`);
-    refreshDlq();
-    refreshOutbox();
+    try {
+      // resolves only once Elasticsearch accepted the document; 502 otherwise
+      await axios.get(`http://localhost:8080/api/retry/${id}`);
+    } finally {
+      refreshDlq();
+      refreshOutbox();
+    }
   }
 
   async function addUser() {
@@ -166,7 +170,7 @@ export default function App() {
                       const id = item.ID ?? item.id;
                       const entity = item.EntityType ?? item.entity_type;
                       const op = item.Op ?? item.op;
-                      const processed = item.Processed ?? item.processed;
+                      const processed = (item.Status ?? item.status) === "done";
                       const created = item.CreatedAt ?? item.created_at;
                       return (
                         <TableRow key={id}>